@@ -0,0 +1,121 @@
+// Package cache redis 客户端的公共接口, goredis 的 GoRedis 和 redigo 的 Redigo.Cache() 都实现了 Cache, 可以互相替换
+// 流水线、订阅、stream 等依赖各自客户端类型的功能不在接口中
+package cache
+
+import (
+	goredis "github.com/go-redis/redis"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+// Iterator SCAN 系列命令的游标迭代器
+// 遍历期间新增或删除的元素可能返回也可能不返回, 同一元素可能返回多次
+type Iterator interface {
+	Next() bool
+	// Val HSCAN 依次返回字段和值, ZSCAN 依次返回成员和分数
+	Val() string
+	Err() error
+}
+
+// IsNil key 或字段不存在时返回的错误, 例如 HGet、ZScore、ZRank
+func IsNil(err error) bool {
+	return err == goredis.Nil || err == redigo.ErrNil
+}
+
+// Cache 与 goredis.GoRedis 的方法一致, 返回值语义相同
+// Eval、EvalSha、Do 的返回值中字符串统一为 string
+type Cache interface {
+	Ping() bool
+	Close() error
+
+	// key
+	Exists(keys []string) (int64, error)
+	Del(keys []string) error
+	// DelPattern 删除匹配的所有 key, 返回删除的数量
+	DelPattern(pattern string) (int64, error)
+	Expire(key string, expiration int) (bool, error)
+	Persist(key string) (bool, error)
+	RenameNX(key, newKey string) (bool, error)
+	Scan(match string, count int64) Iterator
+
+	// string
+	Incr(key string) (int64, error)
+	IncrBy(key string, value int64) (int64, error)
+	Decr(key string) (int64, error)
+	DecrBy(key string, value int64) (int64, error)
+	Set(key string, value interface{}) error
+	SetEx(key string, value interface{}, expiration int) error
+	// SetNx 返回是否设置成功
+	SetNx(key string, value interface{}) (bool, error)
+	SetNxEx(key string, value interface{}, expiration int) (bool, error)
+	// Get key 不存在时返回空字符串
+	Get(key string) (string, error)
+	MSet(keyValues map[string]interface{}) error
+	MSetNx(keyValues map[string]interface{}) error
+	// MGet key 不存在时对应的值为 nil
+	MGet(keys []string) (map[string]interface{}, error)
+
+	// hash
+	HSet(key, field string, value interface{}) error
+	HSetNX(key, field string, value interface{}) error
+	HMSet(key string, fields map[string]interface{}) error
+	HGet(key, field string) (string, error)
+	HMGet(key string, fields []string) ([]interface{}, error)
+	HGetAll(key string) (map[string]string, error)
+	HKeys(key string) ([]string, error)
+	HVals(key string) ([]string, error)
+	HDel(key string, fields []string) error
+	HExists(key, field string) (bool, error)
+	HIncrBy(key, field string, incr int64) (int64, error)
+	HScan(key, match string, count int64) Iterator
+
+	// set
+	SAdd(key string, member interface{}) (int64, error)
+	SAdds(key string, members []interface{}) (int64, error)
+	SCard(key string) (int64, error)
+	SMembers(key string) ([]string, error)
+	SIsMember(key string, member interface{}) (bool, error)
+	SRem(key string, member interface{}) (int64, error)
+	SRems(key string, members []interface{}) (int64, error)
+	SInter(keys []string) ([]string, error)
+	SDiff(keys []string) ([]string, error)
+	SUnion(keys []string) ([]string, error)
+	SScan(key, match string, count int64) Iterator
+
+	// sorted set
+	ZAdd(key string, value string, score int64) (int64, error)
+	ZAdds(key string, valueScore map[string]int64) (int64, error)
+	ZAddNX(key string, value string, score int64) (int64, error)
+	ZAddsNX(key string, valueScore map[string]int64) (int64, error)
+	ZCard(key string) (int64, error)
+	ZCount(key string, min, max int64) (int64, error)
+	ZRange(key string, start, end int64) ([]string, error)
+	ZRangeWithScores(key string, start, end int64) (map[string]int64, error)
+	// ZRangeByScore min、max 为-1时表示无穷
+	ZRangeByScore(key string, min, max int64) ([]string, error)
+	ZRangeByScoreWithScores(key string, min, max int64) (map[string]int64, error)
+	ZRem(key string, fields ...interface{}) (int64, error)
+	ZRemRangeByScore(key string, min, max int64) (int64, error)
+	ZScore(key string, value string) (int64, error)
+	ZIncrBy(key string, value string, incr int64) (int64, error)
+	ZRank(key string, value string) (int64, error)
+	ZRevRank(key string, value string) (int64, error)
+	ZRevRange(key string, start, end int64) ([]string, error)
+	ZScan(key, match string, count int64) Iterator
+
+	// bitmap
+	SetBit(key string, offset int64, value int) (int64, error)
+	GetBit(key string, offset int64) (int64, error)
+	BitCount(key string, start, end int64) (int64, error)
+
+	// hyperloglog
+	PFAdd(key string, els ...interface{}) (int64, error)
+	PFCount(keys ...string) (int64, error)
+	PFMerge(dest string, keys ...string) error
+
+	// script
+	Eval(script string, keys []string, args []interface{}) (interface{}, error)
+	EvalSha(script string, keys []string, args []interface{}) (interface{}, error)
+
+	Publish(channel string, message interface{}) (int64, error)
+	Do(args ...interface{}) (interface{}, error)
+}
@@ -0,0 +1,576 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"time"
+)
+
+// Ctx 后缀的方法将 ctx 传给 hook, 并在发送命令前检查 ctx, 已取消或超时时直接返回 ctx.Err()
+// go-redis v6 不支持按 ctx 中断执行中的命令, 命令发出后同步等待结果,
+// 单个命令的耗时由 RedisConfig 的 ReadTimeout、WriteTimeout 限制
+
+func do[T any](ctx context.Context, cli *GoRedis, fn func(c *GoRedis) (T, error)) (T, error) {
+	if err := ctx.Err(); err != nil {
+		var zero T
+		return zero, err
+	}
+	return fn(cli.WithContext(ctx))
+}
+
+func doErr(ctx context.Context, cli *GoRedis, fn func(c *GoRedis) error) error {
+	_, err := do(ctx, cli, func(c *GoRedis) (struct{}, error) {
+		return struct{}{}, fn(c)
+	})
+	return err
+}
+
+func (cli *GoRedis) PingCtx(ctx context.Context) bool {
+	ok, _ := do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.Ping(), nil
+	})
+	return ok
+}
+
+////////////////////////////////////////
+// key
+////////////////////////////////////////
+
+func (cli *GoRedis) ExistsCtx(ctx context.Context, keys []string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.Exists(keys)
+	})
+}
+
+// DelPatternCtx 在每页 SCAN 和每批 DEL 之前检查 ctx, 取消时返回已删除的数量和 ctx.Err()
+func (cli *GoRedis) DelPatternCtx(ctx context.Context, pattern string) (int64, error) {
+	return cli.WithContext(ctx).delPattern(ctx, pattern)
+}
+
+func (cli *GoRedis) DelCtx(ctx context.Context, keys []string) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.Del(keys)
+	})
+}
+
+func (cli *GoRedis) ExpireCtx(ctx context.Context, key string, expiration int) (bool, error) {
+	return do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.Expire(key, expiration)
+	})
+}
+
+func (cli *GoRedis) PersistCtx(ctx context.Context, key string) (bool, error) {
+	return do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.Persist(key)
+	})
+}
+
+func (cli *GoRedis) RenameNXCtx(ctx context.Context, key, newKey string) (bool, error) {
+	return do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.RenameNX(key, newKey)
+	})
+}
+
+////////////////////////////////////////
+// string
+////////////////////////////////////////
+
+func (cli *GoRedis) IncrCtx(ctx context.Context, key string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.Incr(key)
+	})
+}
+
+func (cli *GoRedis) IncrByCtx(ctx context.Context, key string, value int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.IncrBy(key, value)
+	})
+}
+
+func (cli *GoRedis) DecrCtx(ctx context.Context, key string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.Decr(key)
+	})
+}
+
+func (cli *GoRedis) DecrByCtx(ctx context.Context, key string, value int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.DecrBy(key, value)
+	})
+}
+
+func (cli *GoRedis) SetCtx(ctx context.Context, key string, value interface{}) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.Set(key, value)
+	})
+}
+
+func (cli *GoRedis) SetExCtx(ctx context.Context, key string, value interface{}, expiration int) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.SetEx(key, value, expiration)
+	})
+}
+
+func (cli *GoRedis) SetNxCtx(ctx context.Context, key string, value interface{}) (bool, error) {
+	return do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.SetNx(key, value)
+	})
+}
+
+func (cli *GoRedis) SetNxExCtx(ctx context.Context, key string, value interface{}, expiration int) (bool, error) {
+	return do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.SetNxEx(key, value, expiration)
+	})
+}
+
+func (cli *GoRedis) GetCtx(ctx context.Context, key string) (string, error) {
+	return do(ctx, cli, func(c *GoRedis) (string, error) {
+		return c.Get(key)
+	})
+}
+
+func (cli *GoRedis) MSetCtx(ctx context.Context, keyValues map[string]interface{}) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.MSet(keyValues)
+	})
+}
+
+func (cli *GoRedis) MSetNxCtx(ctx context.Context, keyValues map[string]interface{}) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.MSetNx(keyValues)
+	})
+}
+
+func (cli *GoRedis) MGetCtx(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	return do(ctx, cli, func(c *GoRedis) (map[string]interface{}, error) {
+		return c.MGet(keys)
+	})
+}
+
+////////////////////////////////////////
+// hash
+////////////////////////////////////////
+
+func (cli *GoRedis) HSetCtx(ctx context.Context, key, field string, value interface{}) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.HSet(key, field, value)
+	})
+}
+
+func (cli *GoRedis) HSetNXCtx(ctx context.Context, key, field string, value interface{}) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.HSetNX(key, field, value)
+	})
+}
+
+func (cli *GoRedis) HMSetCtx(ctx context.Context, key string, fields map[string]interface{}) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.HMSet(key, fields)
+	})
+}
+
+func (cli *GoRedis) HGetCtx(ctx context.Context, key, field string) (string, error) {
+	return do(ctx, cli, func(c *GoRedis) (string, error) {
+		return c.HGet(key, field)
+	})
+}
+
+func (cli *GoRedis) HMGetCtx(ctx context.Context, key string, fields []string) ([]interface{}, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]interface{}, error) {
+		return c.HMGet(key, fields)
+	})
+}
+
+func (cli *GoRedis) HGetAllCtx(ctx context.Context, key string) (map[string]string, error) {
+	return do(ctx, cli, func(c *GoRedis) (map[string]string, error) {
+		return c.HGetAll(key)
+	})
+}
+
+func (cli *GoRedis) HKeysCtx(ctx context.Context, key string) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.HKeys(key)
+	})
+}
+
+func (cli *GoRedis) HValsCtx(ctx context.Context, key string) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.HVals(key)
+	})
+}
+
+func (cli *GoRedis) HDelCtx(ctx context.Context, key string, fields []string) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.HDel(key, fields)
+	})
+}
+
+func (cli *GoRedis) HExistsCtx(ctx context.Context, key, field string) (bool, error) {
+	return do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.HExists(key, field)
+	})
+}
+
+func (cli *GoRedis) HIncrByCtx(ctx context.Context, key, field string, incr int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.HIncrBy(key, field, incr)
+	})
+}
+
+////////////////////////////////////////
+// set
+////////////////////////////////////////
+
+func (cli *GoRedis) SAddCtx(ctx context.Context, key string, member interface{}) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.SAdd(key, member)
+	})
+}
+
+func (cli *GoRedis) SAddsCtx(ctx context.Context, key string, members []interface{}) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.SAdds(key, members)
+	})
+}
+
+func (cli *GoRedis) SCardCtx(ctx context.Context, key string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.SCard(key)
+	})
+}
+
+func (cli *GoRedis) SMembersCtx(ctx context.Context, key string) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.SMembers(key)
+	})
+}
+
+func (cli *GoRedis) SIsMemberCtx(ctx context.Context, key string, member interface{}) (bool, error) {
+	return do(ctx, cli, func(c *GoRedis) (bool, error) {
+		return c.SIsMember(key, member)
+	})
+}
+
+func (cli *GoRedis) SRemCtx(ctx context.Context, key string, member interface{}) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.SRem(key, member)
+	})
+}
+
+func (cli *GoRedis) SRemsCtx(ctx context.Context, key string, members []interface{}) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.SRems(key, members)
+	})
+}
+
+func (cli *GoRedis) SInterCtx(ctx context.Context, keys []string) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.SInter(keys)
+	})
+}
+
+func (cli *GoRedis) SDiffCtx(ctx context.Context, keys []string) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.SDiff(keys)
+	})
+}
+
+func (cli *GoRedis) SUnionCtx(ctx context.Context, keys []string) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.SUnion(keys)
+	})
+}
+
+////////////////////////////////////////
+// sorted set
+////////////////////////////////////////
+
+func (cli *GoRedis) ZAddCtx(ctx context.Context, key string, value string, score int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZAdd(key, value, score)
+	})
+}
+
+func (cli *GoRedis) ZAddsCtx(ctx context.Context, key string, valueScore map[string]int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZAdds(key, valueScore)
+	})
+}
+
+func (cli *GoRedis) ZAddNXCtx(ctx context.Context, key string, value string, score int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZAddNX(key, value, score)
+	})
+}
+
+func (cli *GoRedis) ZAddsNXCtx(ctx context.Context, key string, valueScore map[string]int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZAddsNX(key, valueScore)
+	})
+}
+
+func (cli *GoRedis) ZCardCtx(ctx context.Context, key string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZCard(key)
+	})
+}
+
+func (cli *GoRedis) ZCountCtx(ctx context.Context, key string, min, max int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZCount(key, min, max)
+	})
+}
+
+func (cli *GoRedis) ZRangeCtx(ctx context.Context, key string, start, end int64) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.ZRange(key, start, end)
+	})
+}
+
+func (cli *GoRedis) ZRangeWithScoresCtx(ctx context.Context, key string, start, end int64) (map[string]int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (map[string]int64, error) {
+		return c.ZRangeWithScores(key, start, end)
+	})
+}
+
+func (cli *GoRedis) ZRangeByScoreCtx(ctx context.Context, key string, min, max int64) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.ZRangeByScore(key, min, max)
+	})
+}
+
+func (cli *GoRedis) ZRangeByScoreWithScoresCtx(ctx context.Context, key string, min, max int64) (map[string]int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (map[string]int64, error) {
+		return c.ZRangeByScoreWithScores(key, min, max)
+	})
+}
+
+func (cli *GoRedis) ZRemCtx(ctx context.Context, key string, fields ...interface{}) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZRem(key, fields...)
+	})
+}
+
+func (cli *GoRedis) ZRemRangeByScoreCtx(ctx context.Context, key string, min, max int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZRemRangeByScore(key, min, max)
+	})
+}
+
+func (cli *GoRedis) ZScoreCtx(ctx context.Context, key string, value string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZScore(key, value)
+	})
+}
+
+func (cli *GoRedis) ZIncrByCtx(ctx context.Context, key string, value string, incr int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZIncrBy(key, value, incr)
+	})
+}
+
+func (cli *GoRedis) ZRankCtx(ctx context.Context, key string, value string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZRank(key, value)
+	})
+}
+
+func (cli *GoRedis) ZRevRankCtx(ctx context.Context, key string, value string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.ZRevRank(key, value)
+	})
+}
+
+func (cli *GoRedis) ZRevRangeCtx(ctx context.Context, key string, start, end int64) ([]string, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]string, error) {
+		return c.ZRevRange(key, start, end)
+	})
+}
+
+////////////////////////////////////////
+// bitmap
+////////////////////////////////////////
+
+func (cli *GoRedis) SetBitCtx(ctx context.Context, key string, offset int64, value int) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.SetBit(key, offset, value)
+	})
+}
+
+func (cli *GoRedis) GetBitCtx(ctx context.Context, key string, offset int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.GetBit(key, offset)
+	})
+}
+
+func (cli *GoRedis) BitCountCtx(ctx context.Context, key string, start, end int64) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.BitCount(key, start, end)
+	})
+}
+
+////////////////////////////////////////
+// hyperloglog
+////////////////////////////////////////
+
+func (cli *GoRedis) PFAddCtx(ctx context.Context, key string, els ...interface{}) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.PFAdd(key, els...)
+	})
+}
+
+func (cli *GoRedis) PFCountCtx(ctx context.Context, keys ...string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.PFCount(keys...)
+	})
+}
+
+func (cli *GoRedis) PFMergeCtx(ctx context.Context, dest string, keys ...string) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.PFMerge(dest, keys...)
+	})
+}
+
+////////////////////////////////////////
+// geo
+////////////////////////////////////////
+
+func (cli *GoRedis) GeoAddCtx(ctx context.Context, key string, locations ...*redis.GeoLocation) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.GeoAdd(key, locations...)
+	})
+}
+
+func (cli *GoRedis) GeoPosCtx(ctx context.Context, key string, members ...string) ([]*redis.GeoPos, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]*redis.GeoPos, error) {
+		return c.GeoPos(key, members...)
+	})
+}
+
+func (cli *GoRedis) GeoDistCtx(ctx context.Context, key string, member1, member2, unit string) (float64, error) {
+	return do(ctx, cli, func(c *GoRedis) (float64, error) {
+		return c.GeoDist(key, member1, member2, unit)
+	})
+}
+
+func (cli *GoRedis) GeoRadiusCtx(ctx context.Context, key string, longitude, latitude float64, query *redis.GeoRadiusQuery) ([]redis.GeoLocation, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]redis.GeoLocation, error) {
+		return c.GeoRadius(key, longitude, latitude, query)
+	})
+}
+
+func (cli *GoRedis) GeoRadiusByMemberCtx(ctx context.Context, key, member string, query *redis.GeoRadiusQuery) ([]redis.GeoLocation, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]redis.GeoLocation, error) {
+		return c.GeoRadiusByMember(key, member, query)
+	})
+}
+
+////////////////////////////////////////
+// XAdd maxLen 大于0时裁剪, approx 为 true 使用 MAXLEN ~ 按节点裁剪, 性能更好
+////////////////////////////////////////
+
+func (cli *GoRedis) XAddCtx(ctx context.Context, stream string, maxLen int64, approx bool, values map[string]interface{}) (string, error) {
+	return do(ctx, cli, func(c *GoRedis) (string, error) {
+		return c.XAdd(stream, maxLen, approx, values)
+	})
+}
+
+func (cli *GoRedis) XReadGroupCtx(ctx context.Context, group, consumer string, streams, ids []string, count int64, block time.Duration) ([]redis.XStream, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]redis.XStream, error) {
+		return c.XReadGroup(group, consumer, streams, ids, count, block)
+	})
+}
+
+func (cli *GoRedis) XAckCtx(ctx context.Context, stream, group string, ids ...string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.XAck(stream, group, ids...)
+	})
+}
+
+func (cli *GoRedis) XGroupCreateCtx(ctx context.Context, stream, group, start string) error {
+	return doErr(ctx, cli, func(c *GoRedis) error {
+		return c.XGroupCreate(stream, group, start)
+	})
+}
+
+func (cli *GoRedis) XGroupDestroyCtx(ctx context.Context, stream, group string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.XGroupDestroy(stream, group)
+	})
+}
+
+func (cli *GoRedis) XGroupDelConsumerCtx(ctx context.Context, stream, group, consumer string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.XGroupDelConsumer(stream, group, consumer)
+	})
+}
+
+func (cli *GoRedis) XPendingExtCtx(ctx context.Context, stream, group, consumer, start, end string, count int64) ([]redis.XPendingExt, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]redis.XPendingExt, error) {
+		return c.XPendingExt(stream, group, consumer, start, end, count)
+	})
+}
+
+func (cli *GoRedis) XAutoClaimCtx(ctx context.Context, stream, group, consumer string, minIdle time.Duration, start string, count int64) ([]redis.XMessage, []string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, "", err
+	}
+	return cli.WithContext(ctx).XAutoClaim(stream, group, consumer, minIdle, start, count)
+}
+
+func (cli *GoRedis) XTrimCtx(ctx context.Context, stream string, maxLen int64, approx bool) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.XTrim(stream, maxLen, approx)
+	})
+}
+
+func (cli *GoRedis) XTrimMinIdCtx(ctx context.Context, stream, minId string, approx bool) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.XTrimMinId(stream, minId, approx)
+	})
+}
+
+func (cli *GoRedis) XLenCtx(ctx context.Context, stream string) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.XLen(stream)
+	})
+}
+
+////////////////////////////////////////
+// 其他高级属性
+////////////////////////////////////////
+
+func (cli *GoRedis) EvalCtx(ctx context.Context, script string, keys []string, args []interface{}) (interface{}, error) {
+	return do(ctx, cli, func(c *GoRedis) (interface{}, error) {
+		return c.Eval(script, keys, args)
+	})
+}
+
+func (cli *GoRedis) EvalShaCtx(ctx context.Context, script string, keys []string, args []interface{}) (interface{}, error) {
+	return do(ctx, cli, func(c *GoRedis) (interface{}, error) {
+		return c.EvalSha(script, keys, args)
+	})
+}
+
+func (cli *GoRedis) PublishCtx(ctx context.Context, channel string, message interface{}) (int64, error) {
+	return do(ctx, cli, func(c *GoRedis) (int64, error) {
+		return c.Publish(channel, message)
+	})
+}
+
+func (cli *GoRedis) DoCtx(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return do(ctx, cli, func(c *GoRedis) (interface{}, error) {
+		return c.Do(args...)
+	})
+}
+
+// PipelineCtx 流水线命令的 Hook 使用该 ctx, 不支持取消
+func (cli *GoRedis) PipelineCtx(ctx context.Context) redis.Pipeliner {
+	return cli.WithContext(ctx).Pipeline()
+}
+
+func (cli *GoRedis) PipelinedCtx(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	return do(ctx, cli, func(c *GoRedis) ([]redis.Cmder, error) {
+		return c.Pipelined(fn)
+	})
+}
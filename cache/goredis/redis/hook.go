@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"sync"
+	"time"
+)
+
+// Command 传给 Hook 的命令信息, 流水线的 Name 为 pipeline, Args 为其中各命令名
+type Command struct {
+	Name string
+	Args []interface{}
+}
+
+// Hook 命令执行前后的回调, 可用于监控、链路追踪和慢命令日志
+type Hook interface {
+	// Before 返回的 ctx 会传给同一个 Hook 的 After
+	Before(ctx context.Context, cmd *Command) context.Context
+	// After err 不包含 key 不存在(redis.Nil)
+	After(ctx context.Context, cmd *Command, duration time.Duration, err error)
+}
+
+type client interface {
+	redis.Cmdable
+	WrapProcess(fn func(oldProcess func(cmd redis.Cmder) error) func(cmd redis.Cmder) error)
+	WrapProcessPipeline(fn func(oldProcess func([]redis.Cmder) error) func([]redis.Cmder) error)
+}
+
+type hooks struct {
+	mtx  sync.RWMutex
+	list []Hook
+}
+
+func (h *hooks) get() []Hook {
+	h.mtx.RLock()
+	defer h.mtx.RUnlock()
+	return h.list
+}
+
+func (h *hooks) add(hook Hook) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	list := make([]Hook, 0, len(h.list)+1)
+	h.list = append(append(list, h.list...), hook)
+}
+
+// run 按注册顺序调用 Before, 逆序调用 After
+func (h *hooks) run(ctx context.Context, cmd *Command, fn func() error) error {
+	list := h.get()
+	if len(list) <= 0 {
+		return fn()
+	}
+	ctxs := make([]context.Context, len(list))
+	for i, hook := range list {
+		ctx = hook.Before(ctx, cmd)
+		ctxs[i] = ctx
+	}
+	start := time.Now()
+	err := fn()
+	duration := time.Since(start)
+	hookErr := err
+	if hookErr == redis.Nil {
+		hookErr = nil
+	}
+	for i := len(list) - 1; i >= 0; i-- {
+		list[i].After(ctxs[i], cmd, duration, hookErr)
+	}
+	return err
+}
+
+// bind 复制底层客户端并绑定 ctx, 复制出的客户端共享连接池
+func (h *hooks) bind(base client, ctx context.Context) client {
+	var c client
+	switch cli := base.(type) {
+	case *redis.Client:
+		c = cli.WithContext(ctx)
+	case *redis.ClusterClient:
+		c = cli.WithContext(ctx)
+	default:
+		return base
+	}
+	c.WrapProcess(func(old func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			return h.run(ctx, &Command{Name: cmd.Name(), Args: cmd.Args()}, func() error {
+				return old(cmd)
+			})
+		}
+	})
+	c.WrapProcessPipeline(func(old func([]redis.Cmder) error) func([]redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			names := make([]interface{}, 0, len(cmds))
+			for _, cmd := range cmds {
+				names = append(names, cmd.Name())
+			}
+			return h.run(ctx, &Command{Name: "pipeline", Args: names}, func() error {
+				return old(cmds)
+			})
+		}
+	})
+	return c
+}
+
+func newGoRedis(base client) *GoRedis {
+	h := &hooks{}
+	return &GoRedis{redCli: h.bind(base, context.Background()), base: base, hooks: h, ctx: context.Background()}
+}
+
+// AddHook 注册命令回调, 对已经 WithContext 得到的客户端同样生效
+func (cli *GoRedis) AddHook(hook Hook) {
+	cli.hooks.add(hook)
+}
+
+// WithContext 返回绑定 ctx 的客户端, ctx 会传给 Hook; 取消和超时请使用 Ctx 后缀的方法
+func (cli *GoRedis) WithContext(ctx context.Context) *GoRedis {
+	if ctx == nil {
+		panic("nil context")
+	}
+	return &GoRedis{redCli: cli.hooks.bind(cli.base, ctx), base: cli.base, hooks: cli.hooks, ctx: ctx}
+}
+
+func (cli *GoRedis) Context() context.Context {
+	return cli.ctx
+}
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+type recordHook struct {
+	names []string
+	vals  []interface{}
+	errs  []error
+}
+
+func (h *recordHook) Before(ctx context.Context, cmd *Command) context.Context {
+	h.names = append(h.names, cmd.Name)
+	return ctx
+}
+
+func (h *recordHook) After(ctx context.Context, cmd *Command, duration time.Duration, err error) {
+	h.vals = append(h.vals, ctx.Value(ctxKey{}))
+	h.errs = append(h.errs, err)
+}
+
+func TestHook(t *testing.T) {
+	// 不可达地址, 命令失败也会触发回调
+	cli := newGoRedis(redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond}))
+	hook := &recordHook{}
+	cli.AddHook(hook)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "trace")
+	if _, err := cli.WithContext(ctx).Get("k"); err == nil {
+		t.Fatal("want dial error")
+	}
+	if _, err := cli.IncrCtx(ctx, "k"); err == nil {
+		t.Fatal("want dial error")
+	}
+	if len(hook.names) != 2 || hook.names[0] != "get" || hook.names[1] != "incr" {
+		t.Fatalf("names %v", hook.names)
+	}
+	if hook.vals[0] != "trace" || hook.vals[1] != "trace" || hook.errs[0] == nil {
+		t.Fatalf("vals %v errs %v", hook.vals, hook.errs)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := cli.GetCtx(cancelled, "k"); err != context.Canceled {
+		t.Fatalf("want context.Canceled, got %v", err)
+	}
+	if n, err := cli.DelPatternCtx(cancelled, "k*"); n != 0 || err != context.Canceled {
+		t.Fatalf("want context.Canceled, got %v %v", n, err)
+	}
+	if len(hook.names) != 2 {
+		t.Fatalf("cancelled command should not run, names %v", hook.names)
+	}
+}
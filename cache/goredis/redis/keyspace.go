@@ -0,0 +1,142 @@
+package redis
+
+import (
+	"fmt"
+	"github.com/go-redis/redis"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// KeyEvent 键空间通知, 需要服务端开启 notify-keyspace-events, 例如过期事件为 Ex
+type KeyEvent struct {
+	Db    int
+	Key   string
+	Event string // 事件名, 例如 expired、evicted、del、set
+}
+
+// ParseKeyEvent 解析 __keyspace@<db>__:<key> 和 __keyevent@<db>__:<event> 频道的消息
+func ParseKeyEvent(channel, payload string) (*KeyEvent, bool) {
+	var keyspace bool
+	var rest string
+	if strings.HasPrefix(channel, "__keyspace@") {
+		keyspace, rest = true, channel[len("__keyspace@"):]
+	} else if strings.HasPrefix(channel, "__keyevent@") {
+		rest = channel[len("__keyevent@"):]
+	} else {
+		return nil, false
+	}
+	idx := strings.Index(rest, "__:")
+	if idx <= 0 {
+		return nil, false
+	}
+	db, err := strconv.Atoi(rest[:idx])
+	if err != nil {
+		return nil, false
+	}
+	if keyspace {
+		return &KeyEvent{Db: db, Key: rest[idx+3:], Event: payload}, true
+	}
+	return &KeyEvent{Db: db, Key: payload, Event: rest[idx+3:]}, true
+}
+
+// KeyEventSub 键空间通知订阅, 使用完需 Close
+type KeyEventSub struct {
+	list []*redis.PubSub
+	ch   chan *KeyEvent
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// Channel 收到的通知, Close 后关闭
+func (s *KeyEventSub) Channel() <-chan *KeyEvent {
+	return s.ch
+}
+
+func (s *KeyEventSub) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		for _, ps := range s.list {
+			if e := ps.Close(); e != nil {
+				err = e
+			}
+		}
+		s.wg.Wait()
+		close(s.ch)
+	})
+	return err
+}
+
+func (s *KeyEventSub) forward(ps *redis.PubSub) {
+	defer s.wg.Done()
+	for msg := range ps.Channel() {
+		ev, ok := ParseKeyEvent(msg.Channel, msg.Payload)
+		if !ok {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// KeyEvents 订阅指定事件, events 为空时订阅所有事件
+func (cli *GoRedis) KeyEvents(db int, events ...string) (*KeyEventSub, error) {
+	if len(events) <= 0 {
+		events = []string{"*"}
+	}
+	patterns := make([]string, 0, len(events))
+	for _, event := range events {
+		patterns = append(patterns, fmt.Sprintf("__keyevent@%d__:%s", db, event))
+	}
+	return cli.keyEventSub(patterns)
+}
+
+// KeySpace 订阅匹配 key 的所有事件, keys 支持通配符, 为空时订阅所有 key
+func (cli *GoRedis) KeySpace(db int, keys ...string) (*KeyEventSub, error) {
+	if len(keys) <= 0 {
+		keys = []string{"*"}
+	}
+	patterns := make([]string, 0, len(keys))
+	for _, key := range keys {
+		patterns = append(patterns, fmt.Sprintf("__keyspace@%d__:%s", db, key))
+	}
+	return cli.keyEventSub(patterns)
+}
+
+// keyEventSub 集群的每个节点只通知自己的 key, 需订阅所有主节点, 订阅之后新增的节点收不到通知
+func (cli *GoRedis) keyEventSub(patterns []string) (*KeyEventSub, error) {
+	s := &KeyEventSub{ch: make(chan *KeyEvent, 100), done: make(chan struct{})}
+	var err error
+	if c, ok := cli.base.(*redis.ClusterClient); ok {
+		var mtx sync.Mutex
+		err = c.ForEachMaster(func(node *redis.Client) error {
+			ps, err := waitSubscribe(node.PSubscribe(patterns...))
+			if err != nil {
+				return err
+			}
+			mtx.Lock()
+			s.list = append(s.list, ps)
+			mtx.Unlock()
+			return nil
+		})
+	} else {
+		var ps *redis.PubSub
+		if ps, err = cli.PSubscribe(patterns...); err == nil {
+			s.list = append(s.list, ps)
+		}
+	}
+	if err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	for _, ps := range s.list {
+		s.wg.Add(1)
+		go s.forward(ps)
+	}
+	return s, nil
+}
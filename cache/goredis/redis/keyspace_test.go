@@ -0,0 +1,30 @@
+package redis
+
+import (
+	"testing"
+)
+
+func TestParseKeyEvent(t *testing.T) {
+	cases := []struct {
+		channel, payload string
+		want             *KeyEvent
+	}{
+		{"__keyevent@0__:expired", "user:1", &KeyEvent{Db: 0, Key: "user:1", Event: "expired"}},
+		{"__keyspace@3__:order:a:b", "del", &KeyEvent{Db: 3, Key: "order:a:b", Event: "del"}},
+		{"__keyevent@x__:expired", "k", nil},
+		{"__keyspace@__:k", "set", nil},
+		{"news", "hello", nil},
+	}
+	for _, c := range cases {
+		got, ok := ParseKeyEvent(c.channel, c.payload)
+		if c.want == nil {
+			if ok {
+				t.Errorf("%v: want not ok, got %+v", c.channel, got)
+			}
+			continue
+		}
+		if !ok || *got != *c.want {
+			t.Errorf("%v: want %+v, got %+v", c.channel, c.want, got)
+		}
+	}
+}
@@ -1,22 +1,32 @@
 package redis
 
 import (
+	"context"
 	"errors"
 	"github.com/go-redis/redis"
+	"io"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type RedisConfig struct {
-	Addr         []string // 地址
-	Type         string   // node/cluster
-	Pwd          string   // 密码
-	MaxRetries   int      // 最大尝试次数, 默认3
-	MinIdleConns int      // 最初连接数， 默认8
+	Addr            []string      // 地址, sentinel 模式为哨兵地址
+	Type            string        // node/cluster/sentinel
+	Pwd             string        // 密码
+	MaxRetries      int           // 最大尝试次数, 默认3
+	MinIdleConns    int           // 最初连接数， 默认8
+	MasterName      string        // sentinel 模式的主节点名
+	ReadFromReplica bool          // sentinel 模式下读命令发往从节点
+	ReadTimeout     time.Duration // 单个命令读取回复的超时, 默认3秒
+	WriteTimeout    time.Duration // 单个命令写入的超时, 默认同 ReadTimeout
 }
 
 type GoRedis struct {
 	redCli redis.Cmdable
+	base   client
+	hooks  *hooks
+	ctx    context.Context
 }
 
 func checkConfig(conf *RedisConfig) {
@@ -43,22 +53,28 @@ func InitRedis(conf *RedisConfig) (*GoRedis, error) {
 			DB:           0,
 			MaxRetries:   conf.MaxRetries,
 			MinIdleConns: conf.MinIdleConns,
+			ReadTimeout:  conf.ReadTimeout,
+			WriteTimeout: conf.WriteTimeout,
 		})
 		if err := cli.Ping().Err(); err != nil {
 			return nil, err
 		}
-		return &GoRedis{redCli: cli}, nil
+		return newGoRedis(cli), nil
 	} else if conf.Type == "cluster" {
 		cli := redis.NewClusterClient(&redis.ClusterOptions{
 			Addrs:        conf.Addr,
 			Password:     conf.Pwd,
 			MaxRetries:   conf.MaxRetries,
 			MinIdleConns: conf.MinIdleConns,
+			ReadTimeout:  conf.ReadTimeout,
+			WriteTimeout: conf.WriteTimeout,
 		})
 		if err := cli.Ping().Err(); err != nil {
 			return nil, err
 		}
-		return &GoRedis{redCli: cli}, nil
+		return newGoRedis(cli), nil
+	} else if conf.Type == "sentinel" {
+		return initSentinel(conf)
 	} else {
 		return nil, errors.New("config type error")
 	}
@@ -138,8 +154,9 @@ func (cli *GoRedis) SetEx(key string, value interface{}, expiration int) error {
 	return cli.redCli.Set(key, value, time.Duration(expiration)*time.Second).Err()
 }
 
-func (cli *GoRedis) SetNx(key string, value interface{}) error {
-	return cli.redCli.SetNX(key, value, 0).Err()
+// SetNx 返回是否设置成功, key 已存在时返回 false
+func (cli *GoRedis) SetNx(key string, value interface{}) (bool, error) {
+	return cli.redCli.SetNX(key, value, 0).Result()
 }
 
 func (cli *GoRedis) SetNxEx(key string, value interface{}, expiration int) (bool, error) {
@@ -396,6 +413,32 @@ func (cli *GoRedis) ZScore(key string, value string) (int64, error) {
 	return int64(rest), nil
 }
 
+func (cli *GoRedis) ZIncrBy(key string, value string, incr int64) (int64, error) {
+	rest, err := cli.redCli.ZIncrBy(key, float64(incr), value).Result()
+	if err != nil {
+		return 0, err
+	}
+	return int64(rest), nil
+}
+
+// ZRank 按分数从小到大的排名, 从0开始, 成员不存在时返回 redis.Nil
+func (cli *GoRedis) ZRank(key string, value string) (int64, error) {
+	return cli.redCli.ZRank(key, value).Result()
+}
+
+// ZRevRank 按分数从大到小的排名, 从0开始, 成员不存在时返回 redis.Nil
+func (cli *GoRedis) ZRevRank(key string, value string) (int64, error) {
+	return cli.redCli.ZRevRank(key, value).Result()
+}
+
+func (cli *GoRedis) ZRevRange(key string, start, end int64) ([]string, error) {
+	rest, err := cli.redCli.ZRevRange(key, start, end).Result()
+	if err != nil {
+		return nil, err
+	}
+	return rest, nil
+}
+
 ////////////////////////////////////////
 // bitmap
 ////////////////////////////////////////
@@ -415,6 +458,220 @@ func (cli *GoRedis) BitCount(key string, start, end int64) (int64, error) {
 	}).Result()
 }
 
+////////////////////////////////////////
+// hyperloglog
+////////////////////////////////////////
+func (cli *GoRedis) PFAdd(key string, els ...interface{}) (int64, error) {
+	return cli.redCli.PFAdd(key, els...).Result()
+}
+
+// PFCount 多个 key 时返回合并后的基数, 集群模式下 key 需在同一个 slot
+func (cli *GoRedis) PFCount(keys ...string) (int64, error) {
+	return cli.redCli.PFCount(keys...).Result()
+}
+
+func (cli *GoRedis) PFMerge(dest string, keys ...string) error {
+	return cli.redCli.PFMerge(dest, keys...).Err()
+}
+
+////////////////////////////////////////
+// geo
+////////////////////////////////////////
+func (cli *GoRedis) GeoAdd(key string, locations ...*redis.GeoLocation) (int64, error) {
+	return cli.redCli.GeoAdd(key, locations...).Result()
+}
+
+// GeoPos 成员不存在时对应位置为 nil
+func (cli *GoRedis) GeoPos(key string, members ...string) ([]*redis.GeoPos, error) {
+	return cli.redCli.GeoPos(key, members...).Result()
+}
+
+// GeoDist unit 为 m/km/ft/mi, 为空时为 m
+func (cli *GoRedis) GeoDist(key string, member1, member2, unit string) (float64, error) {
+	rest, err := cli.redCli.GeoDist(key, member1, member2, unit).Result()
+	if err != nil && err != redis.Nil {
+		return 0, err
+	}
+	return rest, nil
+}
+
+// GeoRadius 查询范围内的成员, 不保存结果时使用只读命令, 开启 ReadFromReplica 时可发往从节点
+func (cli *GoRedis) GeoRadius(key string, longitude, latitude float64, query *redis.GeoRadiusQuery) ([]redis.GeoLocation, error) {
+	if len(query.Store) <= 0 && len(query.StoreDist) <= 0 {
+		return cli.redCli.GeoRadiusRO(key, longitude, latitude, query).Result()
+	}
+	return cli.redCli.GeoRadius(key, longitude, latitude, query).Result()
+}
+
+func (cli *GoRedis) GeoRadiusByMember(key, member string, query *redis.GeoRadiusQuery) ([]redis.GeoLocation, error) {
+	if len(query.Store) <= 0 && len(query.StoreDist) <= 0 {
+		return cli.redCli.GeoRadiusByMemberRO(key, member, query).Result()
+	}
+	return cli.redCli.GeoRadiusByMember(key, member, query).Result()
+}
+
+////////////////////////////////////////
+// stream
+////////////////////////////////////////
+// XAdd maxLen 大于0时裁剪, approx 为 true 使用 MAXLEN ~ 按节点裁剪, 性能更好
+func (cli *GoRedis) XAdd(stream string, maxLen int64, approx bool, values map[string]interface{}) (string, error) {
+	args := &redis.XAddArgs{Stream: stream, Values: values}
+	if approx {
+		args.MaxLenApprox = maxLen
+	} else {
+		args.MaxLen = maxLen
+	}
+	return cli.redCli.XAdd(args).Result()
+}
+
+// XReadGroup ids 为空时读取新消息(>), block 小于0不阻塞, 为0一直阻塞; 超时没有消息返回空
+func (cli *GoRedis) XReadGroup(group, consumer string, streams, ids []string, count int64, block time.Duration) ([]redis.XStream, error) {
+	args := &redis.XReadGroupArgs{Group: group, Consumer: consumer, Count: count, Block: block}
+	args.Streams = append(args.Streams, streams...)
+	for i := range streams {
+		if i < len(ids) {
+			args.Streams = append(args.Streams, ids[i])
+		} else {
+			args.Streams = append(args.Streams, ">")
+		}
+	}
+	rest, err := cli.redCli.XReadGroup(args).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return rest, nil
+}
+
+func (cli *GoRedis) XAck(stream, group string, ids ...string) (int64, error) {
+	return cli.redCli.XAck(stream, group, ids...).Result()
+}
+
+// XGroupCreate 创建消费组, stream 不存在时自动创建, 消费组已存在时不报错
+func (cli *GoRedis) XGroupCreate(stream, group, start string) error {
+	err := cli.redCli.XGroupCreateMkStream(stream, group, start).Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	return err
+}
+
+func (cli *GoRedis) XGroupDestroy(stream, group string) (int64, error) {
+	return cli.redCli.XGroupDestroy(stream, group).Result()
+}
+
+func (cli *GoRedis) XGroupDelConsumer(stream, group, consumer string) (int64, error) {
+	return cli.redCli.XGroupDelConsumer(stream, group, consumer).Result()
+}
+
+// XPendingExt 查询 [start, end] 内的待确认消息, consumer 为空时查询整个消费组
+func (cli *GoRedis) XPendingExt(stream, group, consumer, start, end string, count int64) ([]redis.XPendingExt, error) {
+	return cli.redCli.XPendingExt(&redis.XPendingExtArgs{
+		Stream:   stream,
+		Group:    group,
+		Start:    start,
+		End:      end,
+		Count:    count,
+		Consumer: consumer,
+	}).Result()
+}
+
+// XAutoClaim 将空闲超过 minIdle 的待确认消息转给 consumer, 返回消息、已从 stream 删除的消息 id
+// 和下次扫描的起始 id, 为 0-0 时扫描完毕. 需要 redis 6.2 以上
+// 6.2 不会从待确认列表移除已删除的消息, 也不返回它们的 id, 因此先以 JUSTID 接管得到全部 id,
+// 再用 XCLAIM 取回仍存在的消息; 调用方需 XAck 已删除的 id, 否则每次扫描都会再次接管
+func (cli *GoRedis) XAutoClaim(stream, group, consumer string, minIdle time.Duration, start string, count int64) ([]redis.XMessage, []string, string, error) {
+	rest, err := cli.Do("xautoclaim", stream, group, consumer, minIdle.Milliseconds(), start, "count", count, "justid")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	vals, ok := rest.([]interface{})
+	if !ok || len(vals) < 2 {
+		return nil, nil, "", errors.New("xautoclaim reply error")
+	}
+	next, _ := vals[0].(string)
+	ids := replyStrings(vals[1])
+	var deleted []string
+	if len(vals) > 2 {
+		// redis 7 返回已删除并已移出待确认列表的 id
+		deleted = replyStrings(vals[2])
+	}
+	if len(ids) <= 0 {
+		return nil, deleted, next, nil
+	}
+	args := []interface{}{"xclaim", stream, group, consumer, 0}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	if rest, err = cli.Do(args...); err != nil {
+		return nil, nil, "", err
+	}
+	entries, _ := rest.([]interface{})
+	msgs := make([]redis.XMessage, 0, len(entries))
+	found := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		fields, ok := entry.([]interface{})
+		if !ok || len(fields) != 2 {
+			continue
+		}
+		id, _ := fields[0].(string)
+		kvs, ok := fields[1].([]interface{})
+		if !ok {
+			continue
+		}
+		values := make(map[string]interface{}, len(kvs)/2)
+		for i := 0; i+1 < len(kvs); i += 2 {
+			if k, ok := kvs[i].(string); ok {
+				values[k] = kvs[i+1]
+			}
+		}
+		msgs = append(msgs, redis.XMessage{ID: id, Values: values})
+		found[id] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	return msgs, deleted, next, nil
+}
+
+func replyStrings(reply interface{}) []string {
+	vals, _ := reply.([]interface{})
+	list := make([]string, 0, len(vals))
+	for _, v := range vals {
+		if s, ok := v.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// XTrim approx 为 true 时按节点裁剪, 实际保留的可能多于 maxLen
+func (cli *GoRedis) XTrim(stream string, maxLen int64, approx bool) (int64, error) {
+	if approx {
+		return cli.redCli.XTrimApprox(stream, maxLen).Result()
+	}
+	return cli.redCli.XTrim(stream, maxLen).Result()
+}
+
+// XTrimMinId 删除 id 小于 minId 的消息, 需要 redis 6.2 以上
+func (cli *GoRedis) XTrimMinId(stream, minId string, approx bool) (int64, error) {
+	args := []interface{}{"xtrim", stream, "minid"}
+	if approx {
+		args = append(args, "~")
+	}
+	rest, err := cli.Do(append(args, minId)...)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := rest.(int64)
+	return n, nil
+}
+
+func (cli *GoRedis) XLen(stream string) (int64, error) {
+	return cli.redCli.XLen(stream).Result()
+}
+
 ////////////////////////////////////////
 // 其他高级属性
 ////////////////////////////////////////
@@ -433,3 +690,57 @@ func (cli *GoRedis) Eval(script string, keys []string, args []interface{}) (inte
 func (cli *GoRedis) EvalSha(script string, keys []string, args []interface{}) (interface{}, error) {
 	return cli.redCli.EvalSha(script, keys, args...).Result()
 }
+
+func (cli *GoRedis) Publish(channel string, message interface{}) (int64, error) {
+	return cli.redCli.Publish(channel, message).Result()
+}
+
+// Subscribe 订阅频道, 断线后 go-redis 会自动重连并重新订阅, 使用完需 Close
+func (cli *GoRedis) Subscribe(channels ...string) (*redis.PubSub, error) {
+	switch c := cli.base.(type) {
+	case *redis.Client:
+		return waitSubscribe(c.Subscribe(channels...))
+	case *redis.ClusterClient:
+		return waitSubscribe(c.Subscribe(channels...))
+	}
+	return nil, errors.New("client not support subscribe")
+}
+
+// PSubscribe 按模式订阅, 例如 news.*, 断线后自动重连并重新订阅, 使用完需 Close
+func (cli *GoRedis) PSubscribe(patterns ...string) (*redis.PubSub, error) {
+	switch c := cli.base.(type) {
+	case *redis.Client:
+		return waitSubscribe(c.PSubscribe(patterns...))
+	case *redis.ClusterClient:
+		return waitSubscribe(c.PSubscribe(patterns...))
+	}
+	return nil, errors.New("client not support subscribe")
+}
+
+// waitSubscribe 等待订阅确认, 确保返回后发布的消息不会丢失
+func waitSubscribe(ps *redis.PubSub) (*redis.PubSub, error) {
+	if _, err := ps.Receive(); err != nil {
+		_ = ps.Close()
+		return nil, err
+	}
+	return ps, nil
+}
+
+// Do 执行任意命令, 用于封装中没有的命令
+func (cli *GoRedis) Do(args ...interface{}) (interface{}, error) {
+	doer, ok := cli.redCli.(interface {
+		Do(args ...interface{}) *redis.Cmd
+	})
+	if !ok {
+		return nil, errors.New("client not support do")
+	}
+	return doer.Do(args...).Result()
+}
+
+// Close 关闭连接池, 由它 WithContext 得到的客户端也不能再使用
+func (cli *GoRedis) Close() error {
+	if c, ok := cli.base.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
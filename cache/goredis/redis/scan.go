@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"context"
+	"github.com/go-redis/redis"
+	"github.com/y1015860449/gotoolkit/cache"
+	"sync"
+)
+
+var _ cache.Cache = (*GoRedis)(nil)
+
+// nodesIterator 依次遍历集群每个主节点
+type nodesIterator struct {
+	list []cache.Iterator
+	err  error
+}
+
+func (it *nodesIterator) Next() bool {
+	for len(it.list) > 0 {
+		if it.list[0].Next() {
+			return true
+		}
+		if err := it.list[0].Err(); err != nil {
+			it.err = err
+			return false
+		}
+		it.list = it.list[1:]
+	}
+	return false
+}
+
+func (it *nodesIterator) Val() string {
+	if len(it.list) <= 0 {
+		return ""
+	}
+	return it.list[0].Val()
+}
+
+func (it *nodesIterator) Err() error {
+	return it.err
+}
+
+// Scan 遍历匹配的 key, count 为每次扫描的数量提示; 集群依次遍历所有主节点
+func (cli *GoRedis) Scan(match string, count int64) cache.Iterator {
+	c, ok := cli.base.(*redis.ClusterClient)
+	if !ok {
+		return cli.redCli.Scan(0, match, count).Iterator()
+	}
+	var mtx sync.Mutex
+	it := &nodesIterator{}
+	it.err = c.ForEachMaster(func(node *redis.Client) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		it.list = append(it.list, node.Scan(0, match, count).Iterator())
+		return nil
+	})
+	if it.err != nil {
+		it.list = nil
+	}
+	return it
+}
+
+func (cli *GoRedis) HScan(key, match string, count int64) cache.Iterator {
+	return cli.redCli.HScan(key, 0, match, count).Iterator()
+}
+
+func (cli *GoRedis) SScan(key, match string, count int64) cache.Iterator {
+	return cli.redCli.SScan(key, 0, match, count).Iterator()
+}
+
+func (cli *GoRedis) ZScan(key, match string, count int64) cache.Iterator {
+	return cli.redCli.ZScan(key, 0, match, count).Iterator()
+}
+
+// DelPattern 按批删除, 集群中的 key 逐个路由到所在节点; 中途出错时已删除的不会恢复
+func (cli *GoRedis) DelPattern(pattern string) (int64, error) {
+	return cli.delPattern(context.Background(), pattern)
+}
+
+// delPattern 每次取 key 和删除前检查 ctx, 取消时返回已删除的数量
+func (cli *GoRedis) delPattern(ctx context.Context, pattern string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	var total int64
+	keys := make([]string, 0, 100)
+	flush := func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var cmds []*redis.IntCmd
+		_, err := cli.redCli.Pipelined(func(p redis.Pipeliner) error {
+			for _, key := range keys {
+				cmds = append(cmds, p.Del(key))
+			}
+			return nil
+		})
+		for _, cmd := range cmds {
+			total += cmd.Val()
+		}
+		keys = keys[:0]
+		return err
+	}
+	it := cli.Scan(pattern, 100)
+	for ctx.Err() == nil && it.Next() {
+		if keys = append(keys, it.Val()); len(keys) >= 100 {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return total, err
+	}
+	if err := ctx.Err(); err != nil {
+		return total, err
+	}
+	if len(keys) > 0 {
+		if err := flush(); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
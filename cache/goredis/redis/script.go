@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"github.com/go-redis/redis"
+	"strings"
+	"sync"
+)
+
+// Script lua 脚本, 创建时计算一次 SHA1
+type Script struct {
+	src string
+	sha string
+}
+
+func NewScript(src string) *Script {
+	sum := sha1.Sum([]byte(src))
+	return &Script{src: src, sha: hex.EncodeToString(sum[:])}
+}
+
+func (s *Script) Hash() string {
+	return s.sha
+}
+
+func (s *Script) Source() string {
+	return s.src
+}
+
+func isNoScript(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOSCRIPT")
+}
+
+// Run 优先 EVALSHA, 脚本未加载时回退到 EVAL, EVAL 执行后服务端会缓存脚本
+func (s *Script) Run(cli *GoRedis, keys []string, args []interface{}) (interface{}, error) {
+	rest, err := cli.EvalSha(s.sha, keys, args)
+	if isNoScript(err) {
+		return cli.Eval(s.src, keys, args)
+	}
+	return rest, err
+}
+
+func (s *Script) RunCtx(ctx context.Context, cli *GoRedis, keys []string, args []interface{}) (interface{}, error) {
+	rest, err := cli.EvalShaCtx(ctx, s.sha, keys, args)
+	if isNoScript(err) {
+		return cli.EvalCtx(ctx, s.src, keys, args)
+	}
+	return rest, err
+}
+
+// Scripts 按名字管理脚本, 启动时可用 Load 预加载到所有节点
+type Scripts struct {
+	mtx  sync.RWMutex
+	list map[string]*Script
+}
+
+func NewScripts() *Scripts {
+	return &Scripts{list: make(map[string]*Script)}
+}
+
+// Register 注册脚本, 同名覆盖
+func (r *Scripts) Register(name, src string) *Script {
+	s := NewScript(src)
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.list[name] = s
+	return s
+}
+
+func (r *Scripts) Get(name string) (*Script, bool) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	s, ok := r.list[name]
+	return s, ok
+}
+
+func (r *Scripts) Run(cli *GoRedis, name string, keys []string, args []interface{}) (interface{}, error) {
+	s, ok := r.Get(name)
+	if !ok {
+		return nil, errors.New("script not registered: " + name)
+	}
+	return s.Run(cli, keys, args)
+}
+
+func (r *Scripts) RunCtx(ctx context.Context, cli *GoRedis, name string, keys []string, args []interface{}) (interface{}, error) {
+	s, ok := r.Get(name)
+	if !ok {
+		return nil, errors.New("script not registered: " + name)
+	}
+	return s.RunCtx(ctx, cli, keys, args)
+}
+
+// Load 将所有已注册的脚本加载到服务端
+func (r *Scripts) Load(cli *GoRedis) error {
+	r.mtx.RLock()
+	list := make([]*Script, 0, len(r.list))
+	for _, s := range r.list {
+		list = append(list, s)
+	}
+	r.mtx.RUnlock()
+	return cli.ScriptLoad(list...)
+}
+
+// forEachMaster 集群对每个主节点执行, 其他模式只执行一次
+func (cli *GoRedis) forEachMaster(fn func(c redis.Cmdable) error) error {
+	if c, ok := cli.base.(*redis.ClusterClient); ok {
+		return c.ForEachMaster(func(node *redis.Client) error {
+			return fn(node)
+		})
+	}
+	return fn(cli.redCli)
+}
+
+// ScriptLoad 预加载脚本, 集群会加载到所有主节点; 故障转移或新增的节点仍依赖 Run 的 EVAL 回退
+func (cli *GoRedis) ScriptLoad(scripts ...*Script) error {
+	return cli.forEachMaster(func(c redis.Cmdable) error {
+		for _, s := range scripts {
+			if err := c.ScriptLoad(s.src).Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ScriptExists 脚本是否已加载, 集群只要有一个主节点未加载即为 false
+func (cli *GoRedis) ScriptExists(scripts ...*Script) ([]bool, error) {
+	hashes := make([]string, 0, len(scripts))
+	for _, s := range scripts {
+		hashes = append(hashes, s.sha)
+	}
+	var mtx sync.Mutex
+	var exists []bool
+	err := cli.forEachMaster(func(c redis.Cmdable) error {
+		rest, err := c.ScriptExists(hashes...).Result()
+		if err != nil {
+			return err
+		}
+		mtx.Lock()
+		defer mtx.Unlock()
+		if exists == nil {
+			exists = rest
+			return nil
+		}
+		for i := range exists {
+			exists[i] = exists[i] && i < len(rest) && rest[i]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exists, nil
+}
+
+////////////////////////////////////////
+// function, 需要 redis 7 以上
+////////////////////////////////////////
+
+// FunctionLoad 加载函数库, 代码需以 #!lua name=<库名> 开头, replace 为 true 时覆盖同名库; 集群会加载到所有主节点
+func (cli *GoRedis) FunctionLoad(code string, replace bool) error {
+	args := []interface{}{"function", "load"}
+	if replace {
+		args = append(args, "replace")
+	}
+	args = append(args, code)
+	return cli.forEachMaster(func(c redis.Cmdable) error {
+		return doCmd(c, args...)
+	})
+}
+
+func (cli *GoRedis) FunctionDelete(library string) error {
+	return cli.forEachMaster(func(c redis.Cmdable) error {
+		return doCmd(c, "function", "delete", library)
+	})
+}
+
+func doCmd(c redis.Cmdable, args ...interface{}) error {
+	doer, ok := c.(interface {
+		Do(args ...interface{}) *redis.Cmd
+	})
+	if !ok {
+		return errors.New("client not support do")
+	}
+	return doer.Do(args...).Err()
+}
+
+// FCall 调用函数, 集群下先发往随机节点再按 MOVED 重定向
+func (cli *GoRedis) FCall(function string, keys []string, args []interface{}) (interface{}, error) {
+	return cli.Do(fcallArgs("fcall", function, keys, args)...)
+}
+
+func (cli *GoRedis) FCallCtx(ctx context.Context, function string, keys []string, args []interface{}) (interface{}, error) {
+	return cli.DoCtx(ctx, fcallArgs("fcall", function, keys, args)...)
+}
+
+// FCallRo 调用只读函数, 函数需声明 no-writes
+func (cli *GoRedis) FCallRo(function string, keys []string, args []interface{}) (interface{}, error) {
+	return cli.Do(fcallArgs("fcall_ro", function, keys, args)...)
+}
+
+func (cli *GoRedis) FCallRoCtx(ctx context.Context, function string, keys []string, args []interface{}) (interface{}, error) {
+	return cli.DoCtx(ctx, fcallArgs("fcall_ro", function, keys, args)...)
+}
+
+func fcallArgs(cmd, function string, keys []string, args []interface{}) []interface{} {
+	list := make([]interface{}, 0, 3+len(keys)+len(args))
+	list = append(list, cmd, function, len(keys))
+	for _, key := range keys {
+		list = append(list, key)
+	}
+	return append(list, args...)
+}
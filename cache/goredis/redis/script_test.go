@@ -0,0 +1,34 @@
+package redis
+
+import (
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	s := NewScript("return 1")
+	if s.Hash() != "e0e1f9fabfc9d4800c877a703b823ac0578ff8db" {
+		t.Fatalf("hash %v", s.Hash())
+	}
+
+	r := NewScripts()
+	r.Register("one", "return 1")
+	if got, ok := r.Get("one"); !ok || got.Hash() != s.Hash() {
+		t.Fatalf("get %+v %v", got, ok)
+	}
+	if _, err := r.Run(nil, "two", nil, nil); err == nil {
+		t.Fatal("want not registered err")
+	}
+}
+
+func TestFcallArgs(t *testing.T) {
+	args := fcallArgs("fcall", "incr_by", []string{"a", "b"}, []interface{}{1})
+	want := []interface{}{"fcall", "incr_by", 2, "a", "b", 1}
+	if len(args) != len(want) {
+		t.Fatalf("args %v", args)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Fatalf("args %v", args)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"errors"
+	"github.com/go-redis/redis"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// readOnlyCmds 开启 ReadFromReplica 时路由到从节点的命令
+// 从节点连接池的每个连接随机连到一个从节点, SCAN 系列的游标只在发出它的节点有效, 因此不路由
+var readOnlyCmds = map[string]bool{
+	"get": true, "mget": true, "strlen": true, "getrange": true, "exists": true, "ttl": true, "pttl": true, "type": true,
+	"hget": true, "hmget": true, "hgetall": true, "hkeys": true, "hvals": true, "hlen": true, "hexists": true,
+	"lrange": true, "llen": true, "lindex": true,
+	"smembers": true, "sismember": true, "scard": true, "srandmember": true,
+	"zrange": true, "zrevrange": true, "zrangebyscore": true, "zrevrangebyscore": true, "zscore": true,
+	"zrank": true, "zrevrank": true, "zcard": true, "zcount": true,
+	"pfcount": true, "geopos": true, "geodist": true, "georadius_ro": true, "georadiusbymember_ro": true,
+}
+
+// initSentinel 主节点由 FailoverClient 在故障转移后自动重新发现
+// ReadFromReplica 时读命令发往从节点, 从节点可能有复制延迟, 从节点连接失败时回退到主节点
+func initSentinel(conf *RedisConfig) (*GoRedis, error) {
+	if len(conf.MasterName) <= 0 {
+		return nil, errors.New("config master name error")
+	}
+	master := redis.NewFailoverClient(&redis.FailoverOptions{
+		MasterName:    conf.MasterName,
+		SentinelAddrs: conf.Addr,
+		Password:      conf.Pwd,
+		MaxRetries:    conf.MaxRetries,
+		MinIdleConns:  conf.MinIdleConns,
+		ReadTimeout:   conf.ReadTimeout,
+		WriteTimeout:  conf.WriteTimeout,
+	})
+	if err := master.Ping().Err(); err != nil {
+		_ = master.Close()
+		return nil, err
+	}
+	if !conf.ReadFromReplica {
+		return newGoRedis(master), nil
+	}
+
+	replica := redis.NewClient(&redis.Options{
+		Addr:         "SentinelReplica",
+		Dialer:       replicaDialer(conf),
+		Password:     conf.Pwd,
+		MaxRetries:   conf.MaxRetries,
+		MinIdleConns: conf.MinIdleConns,
+		ReadTimeout:  conf.ReadTimeout,
+		WriteTimeout: conf.WriteTimeout,
+	})
+	master.WrapProcess(func(old func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			if !readOnlyCmds[strings.ToLower(cmd.Name())] {
+				return old(cmd)
+			}
+			err := replica.Process(cmd)
+			if isConnError(err) {
+				return old(cmd)
+			}
+			return err
+		}
+	})
+	return newGoRedis(master), nil
+}
+
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF || err == errNoReplica {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+var errNoReplica = errors.New("sentinel: no available replica")
+
+// replicaDialer 每次建连时向哨兵查询可用从节点并随机选择一个
+func replicaDialer(conf *RedisConfig) func() (net.Conn, error) {
+	return func() (net.Conn, error) {
+		addrs, err := sentinelReplicas(conf.Addr, conf.MasterName)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) <= 0 {
+			return nil, errNoReplica
+		}
+		return net.DialTimeout("tcp", addrs[rand.Intn(len(addrs))], 5*time.Second)
+	}
+}
+
+// sentinelReplicas 依次询问哨兵, 返回第一个应答的哨兵所知的健康从节点
+func sentinelReplicas(sentinels []string, masterName string) ([]string, error) {
+	var lastErr error
+	for _, addr := range sentinels {
+		sentinel := redis.NewSentinelClient(&redis.Options{
+			Addr:        addr,
+			DialTimeout: 3 * time.Second,
+			ReadTimeout: 3 * time.Second,
+		})
+		cmd := redis.NewSliceCmd("sentinel", "slaves", masterName)
+		err := sentinel.Process(cmd)
+		_ = sentinel.Close()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		return parseReplicas(cmd.Val()), nil
+	}
+	return nil, lastErr
+}
+
+// parseReplicas 解析 SENTINEL SLAVES 的应答, 忽略下线和断开的节点
+func parseReplicas(vals []interface{}) []string {
+	var addrs []string
+	for _, val := range vals {
+		fields, ok := val.([]interface{})
+		if !ok {
+			continue
+		}
+		info := make(map[string]string, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			k, _ := fields[i].(string)
+			v, _ := fields[i+1].(string)
+			info[k] = v
+		}
+		if strings.Contains(info["flags"], "s_down") || strings.Contains(info["flags"], "o_down") ||
+			strings.Contains(info["flags"], "disconnected") || info["master-link-status"] == "err" {
+			continue
+		}
+		if len(info["ip"]) > 0 && len(info["port"]) > 0 {
+			addrs = append(addrs, net.JoinHostPort(info["ip"], info["port"]))
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,171 @@
+// Package redisBloom 基于 redis 位图的布隆过滤器, 多副本共享
+package redisBloom
+
+import (
+	"errors"
+	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/utils"
+	"math"
+)
+
+var (
+	// KEYS[1] 位图; ARGV[1] 每个元素的位数 k, 之后每 k 个为一个元素的偏移
+	// 返回每个元素是否新加入(之前至少有一位为0)
+	addScript = redis.NewScript(`
+		local k = tonumber(ARGV[1])
+		local res = {}
+		for i = 2, #ARGV, k do
+			local added = 0
+			for j = i, i + k - 1 do
+				if redis.call('setbit', KEYS[1], ARGV[j], 1) == 0 then
+					added = 1
+				end
+			end
+			res[#res + 1] = added
+		end
+		return res
+	`)
+	existsScript = redis.NewScript(`
+		local k = tonumber(ARGV[1])
+		local res = {}
+		for i = 2, #ARGV, k do
+			local found = 1
+			for j = i, i + k - 1 do
+				if redis.call('getbit', KEYS[1], ARGV[j]) == 0 then
+					found = 0
+					break
+				end
+			end
+			res[#res + 1] = found
+		end
+		return res
+	`)
+)
+
+// maxBits redis 字符串最大 512MB
+const maxBits = 1 << 32
+
+type Config struct {
+	Capacity      uint64  // 预计元素数量, 默认100万
+	FalsePositive float64 // 达到预计数量时的误判率, 默认0.01
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Capacity:      1000000,
+		FalsePositive: 0.01,
+	}
+}
+
+// Bloom 同一个 key 的所有实例需使用相同配置, 否则位的位置不一致
+type Bloom struct {
+	cli *redis.GoRedis
+	key string
+	m   uint64 // 位数
+	k   int    // 哈希次数
+}
+
+func NewBloom(cli *redis.GoRedis, key string, conf *Config) (*Bloom, error) {
+	if cli == nil || len(key) <= 0 {
+		return nil, errors.New("param is err")
+	}
+	if conf == nil {
+		conf = DefaultConfig()
+	}
+	if conf.Capacity == 0 || conf.FalsePositive <= 0 || conf.FalsePositive >= 1 {
+		return nil, errors.New("config is err")
+	}
+	m, k := optimal(conf.Capacity, conf.FalsePositive)
+	if m > maxBits {
+		return nil, errors.New("config is err")
+	}
+	return &Bloom{cli: cli, key: key, m: m, k: k}, nil
+}
+
+// optimal m = -n*ln(p)/ln2^2, k = m/n*ln2
+func optimal(n uint64, p float64) (uint64, int) {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	k := int(math.Round(m / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return uint64(m), k
+}
+
+// offsets 双重哈希生成 k 个位置
+func (b *Bloom) offsets(data string) []uint64 {
+	h := utils.Hash64([]byte(data))
+	h1, h2 := h&0xffffffff, h>>32|1
+	list := make([]uint64, b.k)
+	for i := range list {
+		list[i] = (h1 + uint64(i)*h2) % b.m
+	}
+	return list
+}
+
+func (b *Bloom) args(items []string) []interface{} {
+	args := make([]interface{}, 0, 1+len(items)*b.k)
+	args = append(args, b.k)
+	for _, item := range items {
+		for _, offset := range b.offsets(item) {
+			args = append(args, offset)
+		}
+	}
+	return args
+}
+
+func (b *Bloom) run(script *redis.Script, items []string) ([]bool, error) {
+	if len(items) <= 0 {
+		return nil, nil
+	}
+	rest, err := script.Run(b.cli, []string{b.key}, b.args(items))
+	if err != nil {
+		return nil, err
+	}
+	vals, ok := rest.([]interface{})
+	if !ok || len(vals) != len(items) {
+		return nil, errors.New("bloom: unexpected script result")
+	}
+	list := make([]bool, len(vals))
+	for i, v := range vals {
+		n, _ := v.(int64)
+		list[i] = n == 1
+	}
+	return list, nil
+}
+
+// Add 返回是否新加入, false 表示可能已经存在
+func (b *Bloom) Add(item string) (bool, error) {
+	list, err := b.run(addScript, []string{item})
+	if err != nil {
+		return false, err
+	}
+	return list[0], nil
+}
+
+func (b *Bloom) MAdd(items ...string) ([]bool, error) {
+	return b.run(addScript, items)
+}
+
+// Exists false 表示一定不存在, true 表示可能存在
+func (b *Bloom) Exists(item string) (bool, error) {
+	list, err := b.run(existsScript, []string{item})
+	if err != nil {
+		return false, err
+	}
+	return list[0], nil
+}
+
+func (b *Bloom) MExists(items ...string) ([]bool, error) {
+	return b.run(existsScript, items)
+}
+
+// Clear 删除位图
+func (b *Bloom) Clear() error {
+	return b.cli.Del([]string{b.key})
+}
+
+// Bits 位图大小和哈希次数
+func (b *Bloom) Bits() (uint64, int) {
+	return b.m, b.k
+}
@@ -0,0 +1,27 @@
+package redisBloom
+
+import (
+	"testing"
+)
+
+func TestOptimal(t *testing.T) {
+	m, k := optimal(1000000, 0.01)
+	// 约 9.59 位/元素, 7 次哈希
+	if m < 9500000 || m > 9600000 || k != 7 {
+		t.Fatalf("m %v k %v", m, k)
+	}
+}
+
+func TestOffsets(t *testing.T) {
+	b := &Bloom{m: 1000, k: 5}
+	a1, a2 := b.offsets("a"), b.offsets("a")
+	for i := range a1 {
+		if a1[i] != a2[i] || a1[i] >= b.m {
+			t.Fatalf("offsets %v %v", a1, a2)
+		}
+	}
+	args := b.args([]string{"a", "b"})
+	if len(args) != 1+2*b.k || args[0] != b.k {
+		t.Fatalf("args %v", args)
+	}
+}
@@ -0,0 +1,250 @@
+package redisCache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	goredis "github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"golang.org/x/sync/singleflight"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound loader 返回该错误表示数据不存在, 会按 NegativeTTL 缓存空结果
+var ErrNotFound = errors.New("redisCache: not found")
+
+// Marshaler 缓存值的序列化方式
+type Marshaler interface {
+	Marshal(interface{}) ([]byte, error)
+	Unmarshal([]byte, interface{}) error
+}
+
+type jsonMarshaler struct{}
+
+func (jsonMarshaler) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type AsideConfig struct {
+	TTL         time.Duration // 缓存时间, 默认10分钟
+	NegativeTTL time.Duration // 空结果缓存时间, 为0时默认1分钟, 小于0不缓存
+	Jitter      float64       // 缓存时间随机增加 [0, TTL*Jitter), 避免同时过期, 默认0.1
+	Beta        float64       // 提前刷新系数, 越大越早刷新, 为0关闭提前刷新, 默认1
+	Timeout     time.Duration // 加载的超时时间, 加载由并发的调用共享, 不受单个调用方 ctx 取消的影响, 默认5秒
+	Marshaler   Marshaler     // 序列化方式, 默认 json
+}
+
+func DefaultAsideConfig() *AsideConfig {
+	return &AsideConfig{
+		TTL:         10 * time.Minute,
+		NegativeTTL: time.Minute,
+		Jitter:      0.1,
+		Beta:        1,
+		Timeout:     5 * time.Second,
+		Marshaler:   jsonMarshaler{},
+	}
+}
+
+/*
+Aside 旁路缓存, 使用 GetOrLoad 读取
+redis 中的值格式为 "过期时间毫秒:加载耗时毫秒:标记:数据", 标记 v 为有值, n 为空结果
+按加载耗时做概率性提前刷新(XFetch), 临近过期时少量请求在后台重新加载, 避免过期瞬间大量请求回源
+*/
+type Aside struct {
+	cli   *goredis.GoRedis
+	conf  AsideConfig
+	group singleflight.Group
+}
+
+func NewAside(cli *goredis.GoRedis, conf *AsideConfig) (*Aside, error) {
+	if cli == nil {
+		return nil, errors.New("param is err")
+	}
+	if conf == nil {
+		conf = DefaultAsideConfig()
+	}
+	if conf.TTL < time.Second || conf.Jitter < 0 || conf.Beta < 0 {
+		return nil, errors.New("config is err")
+	}
+	if conf.NegativeTTL == 0 {
+		conf.NegativeTTL = time.Minute
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 5 * time.Second
+	}
+	if conf.Marshaler == nil {
+		conf.Marshaler = jsonMarshaler{}
+	}
+	return &Aside{cli: cli, conf: *conf}, nil
+}
+
+type envelope struct {
+	expireAt int64 // 毫秒
+	delta    int64 // 毫秒
+	found    bool
+	data     string
+}
+
+func (e *envelope) encode() string {
+	flag := "n"
+	if e.found {
+		flag = "v"
+	}
+	return strconv.FormatInt(e.expireAt, 10) + ":" + strconv.FormatInt(e.delta, 10) + ":" + flag + ":" + e.data
+}
+
+func decodeEnvelope(raw string) (*envelope, bool) {
+	parts := strings.SplitN(raw, ":", 4)
+	if len(parts) != 4 {
+		return nil, false
+	}
+	expireAt, err1 := strconv.ParseInt(parts[0], 10, 64)
+	delta, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil || (parts[2] != "v" && parts[2] != "n") {
+		return nil, false
+	}
+	return &envelope{expireAt: expireAt, delta: delta, found: parts[2] == "v", data: parts[3]}, true
+}
+
+// shouldRefresh XFetch: now - delta*beta*ln(rand) >= expireAt 时提前刷新
+func (a *Aside) shouldRefresh(e *envelope, now time.Time) bool {
+	if a.conf.Beta <= 0 {
+		return false
+	}
+	gap := -float64(e.delta) * a.conf.Beta * math.Log(1-rand.Float64())
+	return float64(now.UnixMilli())+gap >= float64(e.expireAt)
+}
+
+func (a *Aside) ttl(found bool) time.Duration {
+	if !found {
+		return a.conf.NegativeTTL
+	}
+	ttl := a.conf.TTL
+	if a.conf.Jitter > 0 {
+		ttl += time.Duration(rand.Float64() * a.conf.Jitter * float64(ttl))
+	}
+	return ttl
+}
+
+// store 写入 redis, 空结果且 NegativeTTL 小于0时不写
+func (a *Aside) store(ctx context.Context, key string, e *envelope, start time.Time) {
+	ttl := a.ttl(e.found)
+	if ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	e.expireAt = now.Add(ttl).UnixMilli()
+	e.delta = now.Sub(start).Milliseconds()
+	secs := int((ttl + time.Second - 1) / time.Second)
+	if err := a.cli.SetExCtx(ctx, key, e.encode(), secs); err != nil {
+		log.Printf("cache aside set key(%v) err(%+v)", key, err)
+	}
+}
+
+// Del 删除缓存, 数据更新后调用
+func (a *Aside) Del(ctx context.Context, keys ...string) error {
+	return a.cli.DelCtx(ctx, keys)
+}
+
+// Set 主动写入缓存
+func Set[T any](ctx context.Context, a *Aside, key string, value T) error {
+	data, err := a.conf.Marshaler.Marshal(value)
+	if err != nil {
+		return err
+	}
+	e := &envelope{found: true, data: string(data)}
+	start := time.Now()
+	ttl := a.ttl(true)
+	e.expireAt = start.Add(ttl).UnixMilli()
+	return a.cli.SetExCtx(ctx, key, e.encode(), int((ttl+time.Second-1)/time.Second))
+}
+
+// GetOrLoad 读取缓存, 未命中时调用 loader 加载并写入, 同一实例内同一 key 的并发加载合并为一次
+// 数据不存在时 loader 应返回 ErrNotFound, 之后 NegativeTTL 内直接返回 ErrNotFound
+// loader 的 ctx 与调用方无关, 超时时间为 Timeout; 调用方 ctx 取消时只是该调用提前返回
+func GetOrLoad[T any](ctx context.Context, a *Aside, key string, loader func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	raw, err := a.cli.GetCtx(ctx, key)
+	if err != nil {
+		if ctx.Err() != nil {
+			return zero, err
+		}
+		log.Printf("cache aside get key(%v) err(%+v)", key, err)
+	}
+	if e, ok := decodeEnvelope(raw); ok {
+		v, err := decode[T](a, e)
+		if err == nil || err == ErrNotFound {
+			if a.shouldRefresh(e, time.Now()) {
+				go refresh(a, key, loader)
+			}
+			return v, err
+		}
+		// 数据结构变更等导致无法解析时重新加载
+		log.Printf("cache aside decode key(%v) err(%+v)", key, err)
+	}
+
+	ch := a.group.DoChan(key, func() (interface{}, error) {
+		// 合并后的加载可能服务多个调用方, 不使用第一个调用方的 ctx, 避免其取消导致所有调用方失败
+		lctx, cancel := context.WithTimeout(context.Background(), a.conf.Timeout)
+		defer cancel()
+		return load(lctx, a, key, loader)
+	})
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return decode[T](a, res.Val.(*envelope))
+	}
+}
+
+func decode[T any](a *Aside, e *envelope) (T, error) {
+	var v T
+	if !e.found {
+		return v, ErrNotFound
+	}
+	if err := a.conf.Marshaler.Unmarshal([]byte(e.data), &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+func load[T any](ctx context.Context, a *Aside, key string, loader func(ctx context.Context) (T, error)) (*envelope, error) {
+	start := time.Now()
+	v, err := loader(ctx)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	e := &envelope{found: err == nil}
+	if e.found {
+		data, err := a.conf.Marshaler.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		e.data = string(data)
+	}
+	a.store(ctx, key, e, start)
+	return e, nil
+}
+
+// refresh 后台刷新, 与同 key 的其他加载合并
+func refresh[T any](a *Aside, key string, loader func(ctx context.Context) (T, error)) {
+	_, err, _ := a.group.Do(key, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), a.conf.Timeout)
+		defer cancel()
+		return load(ctx, a, key, loader)
+	})
+	if err != nil {
+		log.Printf("cache aside refresh key(%v) err(%+v)", key, err)
+	}
+}
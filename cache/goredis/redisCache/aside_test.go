@@ -0,0 +1,59 @@
+package redisCache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnvelope(t *testing.T) {
+	e := &envelope{expireAt: 1700000000000, delta: 12, found: true, data: `{"a":"x:y"}`}
+	got, ok := decodeEnvelope(e.encode())
+	if !ok || *got != *e {
+		t.Fatalf("decode %+v %v", got, ok)
+	}
+	for _, raw := range []string{"", "plain", "1:2:x:data", "a:2:v:data"} {
+		if _, ok := decodeEnvelope(raw); ok {
+			t.Fatalf("%q should be invalid", raw)
+		}
+	}
+
+	a := &Aside{conf: *DefaultAsideConfig()}
+	type user struct{ Name string }
+	v, err := decode[user](a, &envelope{found: true, data: `{"Name":"tom"}`})
+	if err != nil || v.Name != "tom" {
+		t.Fatalf("decode %+v %v", v, err)
+	}
+	if _, err = decode[user](a, &envelope{}); err != ErrNotFound {
+		t.Fatalf("want ErrNotFound, got %v", err)
+	}
+}
+
+func TestAsideRefresh(t *testing.T) {
+	a := &Aside{conf: *DefaultAsideConfig()}
+	now := time.Now()
+	// 离过期很远不刷新, 已过期一定刷新
+	far := &envelope{expireAt: now.Add(time.Hour).UnixMilli(), delta: 10}
+	expired := &envelope{expireAt: now.UnixMilli() - 1, delta: 10}
+	for i := 0; i < 1000; i++ {
+		if a.shouldRefresh(far, now) {
+			t.Fatal("refresh too early")
+		}
+		if !a.shouldRefresh(expired, now) {
+			t.Fatal("expired entry not refreshed")
+		}
+	}
+	a.conf.Beta = 0
+	if a.shouldRefresh(expired, now) {
+		t.Fatal("beta 0 should disable refresh")
+	}
+
+	for i := 0; i < 1000; i++ {
+		ttl := a.ttl(true)
+		if ttl < a.conf.TTL || ttl >= a.conf.TTL+a.conf.TTL/10 {
+			t.Fatalf("ttl %v out of jitter range", ttl)
+		}
+	}
+	if a.ttl(false) != a.conf.NegativeTTL {
+		t.Fatal("negative ttl")
+	}
+}
@@ -0,0 +1,230 @@
+// Package redisCache 基于 redis 的缓存: 本地 localCache + redis 两级缓存 Cache, 多副本通过 pub/sub 广播失效; 带类型的旁路缓存 Aside
+package redisCache
+
+import (
+	"errors"
+	"github.com/go-redis/redis"
+	goredis "github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/cache/localCache"
+	"github.com/y1015860449/gotoolkit/utils"
+	"golang.org/x/sync/singleflight"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Config struct {
+	Size     int           // 本地最多缓存条数, 默认10000
+	LocalTTL time.Duration // 本地缓存时间上限, 失效消息丢失时最多读到该时长的旧值, 默认1分钟
+	Channel  string        // 失效消息频道, 共享缓存的服务需相同, 默认 gotoolkit:cache:invalidate
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Size:     10000,
+		LocalTTL: time.Minute,
+		Channel:  "gotoolkit:cache:invalidate",
+	}
+}
+
+// Loader 缓存未命中时加载数据
+type Loader func() (string, error)
+
+// remote Cache 用到的 redis 命令, 由 goredis.GoRedis 实现
+type remote interface {
+	MGet(keys []string) (map[string]interface{}, error)
+	Set(key string, value interface{}) error
+	SetEx(key string, value interface{}, expiration int) error
+	Del(keys []string) error
+	Publish(channel string, message interface{}) (int64, error)
+}
+
+type Cache struct {
+	cli   remote
+	conf  Config
+	id    string
+	local *localCache.Cache[string, string]
+	group singleflight.Group
+	ps    *redis.PubSub
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewCache 订阅失效频道, 订阅失败返回错误
+func NewCache(cli *goredis.GoRedis, conf *Config) (*Cache, error) {
+	if cli == nil {
+		return nil, errors.New("param is err")
+	}
+	if conf == nil {
+		conf = DefaultConfig()
+	}
+	if conf.Size <= 0 || conf.LocalTTL <= 0 || len(conf.Channel) <= 0 {
+		return nil, errors.New("config is err")
+	}
+	local, err := localCache.New[string, string](&localCache.Config{MaxCost: int64(conf.Size), TTL: conf.LocalTTL}, nil)
+	if err != nil {
+		return nil, err
+	}
+	ps, err := cli.Subscribe(conf.Channel)
+	if err != nil {
+		local.Close()
+		return nil, err
+	}
+	c := &Cache{
+		cli:   cli,
+		conf:  *conf,
+		id:    utils.GetUUID(),
+		local: local,
+		ps:    ps,
+		done:  make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.listen()
+	return c, nil
+}
+
+// listen 失效消息格式为 "实例id|key", 忽略自己发出的消息
+// 重连后会再次收到订阅确认, 期间的消息可能丢失, 此时清空本地缓存
+func (c *Cache) listen() {
+	defer c.wg.Done()
+	for {
+		msg, err := c.ps.Receive()
+		if err != nil {
+			select {
+			case <-c.done:
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+			c.local.Clear()
+			continue
+		}
+		c.handle(msg)
+	}
+}
+
+func (c *Cache) handle(msg interface{}) {
+	switch m := msg.(type) {
+	case *redis.Subscription:
+		c.local.Clear()
+	case *redis.Message:
+		if idx := strings.IndexByte(m.Payload, '|'); idx > 0 && m.Payload[:idx] != c.id {
+			c.local.Delete(m.Payload[idx+1:])
+		}
+	}
+}
+
+func (c *Cache) localTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > c.conf.LocalTTL {
+		return c.conf.LocalTTL
+	}
+	return ttl
+}
+
+// Get 依次查询本地和 redis, 不存在时 ok 为 false
+func (c *Cache) Get(key string) (string, bool, error) {
+	if value, ok := c.local.Get(key); ok {
+		return value, true, nil
+	}
+	return c.getRemote(key)
+}
+
+func (c *Cache) getRemote(key string) (string, bool, error) {
+	// GoRedis.Get 不区分 key 不存在和空字符串, 使用 MGet
+	values, err := c.cli.MGet([]string{key})
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := values[key].(string)
+	if !ok {
+		return "", false, nil
+	}
+	c.local.Set(key, value)
+	return value, true, nil
+}
+
+// setRemote ttl 不足1秒按1秒, 小于等于0不过期
+func (c *Cache) setRemote(key, value string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return c.cli.Set(key, value)
+	}
+	return c.cli.SetEx(key, value, int((ttl+time.Second-1)/time.Second))
+}
+
+// Set 写入 redis 并通知其他实例删除本地缓存, ttl 为 redis 过期时间
+func (c *Cache) Set(key, value string, ttl time.Duration) error {
+	if err := c.setRemote(key, value, ttl); err != nil {
+		return err
+	}
+	c.local.SetWithTTL(key, value, c.localTTL(ttl))
+	c.invalidate(key)
+	return nil
+}
+
+// Del 删除 redis 和所有实例的本地缓存
+func (c *Cache) Del(keys ...string) error {
+	if err := c.cli.Del(keys); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		c.local.Delete(key)
+		c.invalidate(key)
+	}
+	return nil
+}
+
+// Invalidate 只删除所有实例的本地缓存, 用于其他途径修改了 redis 的场景
+func (c *Cache) Invalidate(keys ...string) {
+	for _, key := range keys {
+		c.local.Delete(key)
+		c.invalidate(key)
+	}
+}
+
+func (c *Cache) invalidate(key string) {
+	if _, err := c.cli.Publish(c.conf.Channel, c.id+"|"+key); err != nil {
+		log.Printf("cache invalidate key(%v) err(%+v)", key, err)
+	}
+}
+
+// GetOrLoad 未命中时调用 loader 并写入缓存, 同一实例内同一 key 的并发未命中只加载一次
+func (c *Cache) GetOrLoad(key string, ttl time.Duration, loader Loader) (string, error) {
+	if value, ok := c.local.Get(key); ok {
+		return value, nil
+	}
+	v, err, _ := c.group.Do(key, func() (interface{}, error) {
+		value, ok, err := c.getRemote(key)
+		if err != nil {
+			log.Printf("cache get key(%v) err(%+v)", key, err)
+		} else if ok {
+			return value, nil
+		}
+		value, err = loader()
+		if err != nil {
+			return "", err
+		}
+		// 新加载的值其他实例本地不会有, 无需广播
+		if err = c.setRemote(key, value, ttl); err != nil {
+			log.Printf("cache set key(%v) err(%+v)", key, err)
+		}
+		c.local.SetWithTTL(key, value, c.localTTL(ttl))
+		return value, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+// Len 本地缓存条数
+func (c *Cache) Len() int {
+	return c.local.Len()
+}
+
+func (c *Cache) Close() error {
+	close(c.done)
+	err := c.ps.Close()
+	c.wg.Wait()
+	c.local.Close()
+	return err
+}
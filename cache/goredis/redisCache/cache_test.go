@@ -0,0 +1,132 @@
+package redisCache
+
+import (
+	"github.com/go-redis/redis"
+	"github.com/y1015860449/gotoolkit/cache/localCache"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeRemote struct {
+	mtx       sync.Mutex
+	data      map[string]string
+	published []string
+}
+
+func (f *fakeRemote) MGet(keys []string) (map[string]interface{}, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	values := make(map[string]interface{})
+	for _, key := range keys {
+		if v, ok := f.data[key]; ok {
+			values[key] = v
+		} else {
+			values[key] = nil
+		}
+	}
+	return values, nil
+}
+
+func (f *fakeRemote) Set(key string, value interface{}) error {
+	return f.SetEx(key, value, 0)
+}
+
+func (f *fakeRemote) SetEx(key string, value interface{}, expiration int) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.data[key] = value.(string)
+	return nil
+}
+
+func (f *fakeRemote) Del(keys []string) error {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	for _, key := range keys {
+		delete(f.data, key)
+	}
+	return nil
+}
+
+func (f *fakeRemote) Publish(channel string, message interface{}) (int64, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.published = append(f.published, message.(string))
+	return 1, nil
+}
+
+func newTestCache(t *testing.T) (*Cache, *fakeRemote) {
+	local, err := localCache.New[string, string](&localCache.Config{MaxCost: 100, TTL: time.Minute}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(local.Close)
+	f := &fakeRemote{data: make(map[string]string)}
+	return &Cache{cli: f, conf: *DefaultConfig(), id: "self", local: local}, f
+}
+
+func TestCacheHandle(t *testing.T) {
+	c, f := newTestCache(t)
+	if err := c.Set("a", "1", 0); err != nil {
+		t.Fatal(err)
+	}
+	c.local.Set("b", "2")
+	if len(f.published) != 1 || f.published[0] != "self|a" {
+		t.Fatalf("published %v", f.published)
+	}
+
+	// 自己发出的消息和格式错误的消息忽略
+	for _, payload := range []string{"self|a", "a", "|a"} {
+		c.handle(&redis.Message{Payload: payload})
+	}
+	if c.Len() != 2 {
+		t.Fatalf("len %d", c.Len())
+	}
+	c.handle(&redis.Message{Payload: "other|a"})
+	if _, ok := c.local.Get("a"); ok || c.Len() != 1 {
+		t.Fatalf("a should be invalidated, len %d", c.Len())
+	}
+	// 重新订阅期间可能丢失消息, 清空本地缓存
+	c.handle(&redis.Subscription{Kind: "subscribe", Channel: c.conf.Channel})
+	if c.Len() != 0 {
+		t.Fatalf("len %d after resubscribe", c.Len())
+	}
+	// 本地已清空, 仍可从 redis 读到
+	if v, ok, err := c.Get("a"); err != nil || !ok || v != "1" {
+		t.Fatalf("get %v %v %v", v, ok, err)
+	}
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	c, f := newTestCache(t)
+	var calls int32
+	loader := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		return "v", nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := c.GetOrLoad("k", time.Minute, loader); err != nil || v != "v" {
+				t.Errorf("load %v %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Fatalf("loader called %d times", calls)
+	}
+	if f.data["k"] != "v" || len(f.published) != 0 {
+		t.Fatalf("data %v published %v", f.data, f.published)
+	}
+
+	// redis 中已有的值不再加载
+	f.data["r"] = "remote"
+	if v, err := c.GetOrLoad("r", time.Minute, loader); err != nil || v != "remote" || calls != 1 {
+		t.Fatalf("load %v %v calls %d", v, err, calls)
+	}
+}
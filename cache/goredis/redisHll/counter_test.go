@@ -0,0 +1,21 @@
+package redisHll
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounterIndex(t *testing.T) {
+	c := &Counter{name: "dau", bucket: 24 * time.Hour}
+	loc := time.FixedZone("CST", 8*3600)
+	// 本地时间同一天的零点和 23:59 在同一个桶
+	d1 := time.Date(2024, 3, 1, 0, 0, 0, 0, loc)
+	d2 := time.Date(2024, 3, 1, 23, 59, 59, 0, loc)
+	d3 := time.Date(2024, 3, 2, 0, 0, 0, 0, loc)
+	if c.index(d1) != c.index(d2) || c.index(d3) != c.index(d1)+1 {
+		t.Fatalf("index %v %v %v", c.index(d1), c.index(d2), c.index(d3))
+	}
+	if key := c.key(c.index(d1)); key != "{dau}:19783" {
+		t.Fatalf("key %v", key)
+	}
+}
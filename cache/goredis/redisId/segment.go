@@ -0,0 +1,31 @@
+// Package redisId 基于 redis 的 id 分配
+package redisId
+
+import (
+	"context"
+	"errors"
+	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
+)
+
+// SegmentStore 号段模式的 redis 存储, 实现 idMaker.SegmentStore
+// 依赖 redis 持久化, AOF 未开启时宕机可能导致 id 重复, 对连续性要求高的场景请使用 mysql 存储
+type SegmentStore struct {
+	cli    *redis.GoRedis
+	prefix string
+}
+
+// NewSegmentStore 每个 tag 的最大 id 存在 prefix+tag 中
+func NewSegmentStore(cli *redis.GoRedis, prefix string) (*SegmentStore, error) {
+	if cli == nil {
+		return nil, errors.New("param is err")
+	}
+	return &SegmentStore{cli: cli, prefix: prefix}, nil
+}
+
+func (s *SegmentStore) Reserve(ctx context.Context, tag string, step int64) (int64, int64, error) {
+	maxId, err := s.cli.IncrByCtx(ctx, s.prefix+tag, step)
+	if err != nil {
+		return 0, 0, err
+	}
+	return maxId - step + 1, maxId, nil
+}
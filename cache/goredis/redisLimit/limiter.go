@@ -0,0 +1,158 @@
+// Package redisLimit 基于 redis lua 脚本的分布式限流, 多副本共享配额
+package redisLimit
+
+import (
+	"context"
+	"errors"
+	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/ratelimit"
+	"strconv"
+	"time"
+)
+
+const (
+	// KEYS[1] 桶; ARGV[1] 每毫秒产生的令牌数, ARGV[2] 桶容量, ARGV[3] 请求数
+	// 返回 {是否放行, 剩余令牌, 需等待毫秒(-1为永不)}
+	tokenBucketScript = `
+		if redis.replicate_commands then redis.replicate_commands() end
+		local rate = tonumber(ARGV[1])
+		local burst = tonumber(ARGV[2])
+		local n = tonumber(ARGV[3])
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+		local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+		local tokens = tonumber(data[1]) or burst
+		local ts = tonumber(data[2]) or now
+		if now > ts then
+			tokens = math.min(burst, tokens + (now - ts) * rate)
+			ts = now
+		end
+		local allowed, retry = 0, 0
+		if n > burst then
+			retry = -1
+		elseif tokens >= n then
+			tokens = tokens - n
+			allowed = 1
+		else
+			retry = math.ceil((n - tokens) / rate)
+		end
+		redis.call('HMSET', KEYS[1], 'tokens', tokens, 'ts', ts)
+		redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate) + 1000)
+		return {allowed, math.floor(tokens), retry}
+	`
+	// KEYS[1] 窗口; ARGV[1] 窗口内上限, ARGV[2] 窗口毫秒, ARGV[3] 请求数
+	slidingWindowScript = `
+		if redis.replicate_commands then redis.replicate_commands() end
+		local limit = tonumber(ARGV[1])
+		local size = tonumber(ARGV[2])
+		local n = tonumber(ARGV[3])
+		local t = redis.call('TIME')
+		local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+		local start = now - now % size
+		local data = redis.call('HMGET', KEYS[1], 'start', 'cur', 'prev')
+		local s = tonumber(data[1]) or start
+		local cur = tonumber(data[2]) or 0
+		local prev = tonumber(data[3]) or 0
+		if s ~= start then
+			if start - s == size then prev = cur else prev = 0 end
+			cur = 0
+		end
+		local elapsed = now - start
+		local count = prev * (size - elapsed) / size + cur
+		local remaining = math.max(0, math.floor(limit - count))
+		if n > limit then
+			return {0, remaining, -1}
+		end
+		if count + n > limit then
+			if cur + n > limit or prev == 0 then
+				return {0, remaining, size - elapsed}
+			end
+			local retry = math.ceil((1 - (limit - cur - n) / prev) * size) - elapsed
+			return {0, remaining, math.max(1, retry)}
+		end
+		cur = cur + n
+		redis.call('HMSET', KEYS[1], 'start', start, 'cur', cur, 'prev', prev)
+		redis.call('PEXPIRE', KEYS[1], size * 2)
+		return {1, math.max(0, remaining - n), 0}
+	`
+)
+
+var (
+	tokenBucket   = redis.NewScript(tokenBucketScript)
+	slidingWindow = redis.NewScript(slidingWindowScript)
+)
+
+func parseResult(rest interface{}) (*ratelimit.Result, error) {
+	vals, ok := rest.([]interface{})
+	if !ok || len(vals) != 3 {
+		return nil, errors.New("ratelimit: unexpected script result")
+	}
+	allowed, _ := vals[0].(int64)
+	remaining, _ := vals[1].(int64)
+	retry, _ := vals[2].(int64)
+	res := &ratelimit.Result{Allowed: allowed == 1, Remaining: remaining}
+	if retry < 0 {
+		res.RetryAfter = -1
+	} else {
+		res.RetryAfter = time.Duration(retry) * time.Millisecond
+	}
+	return res, nil
+}
+
+// TokenBucket 分布式令牌桶, 时间以 redis 服务器为准
+type TokenBucket struct {
+	cli    *redis.GoRedis
+	prefix string
+	limit  ratelimit.Limit
+}
+
+// NewTokenBucket prefix 为 key 前缀, 实际 key 为 prefix+key
+func NewTokenBucket(cli *redis.GoRedis, prefix string, limit ratelimit.Limit) (*TokenBucket, error) {
+	limit, err := limit.Check()
+	if err != nil {
+		return nil, err
+	}
+	if limit.Period < time.Millisecond {
+		return nil, ratelimit.ErrLimit
+	}
+	return &TokenBucket{cli: cli, prefix: prefix, limit: limit}, nil
+}
+
+func (tb *TokenBucket) AllowN(ctx context.Context, key string, n int64) (*ratelimit.Result, error) {
+	rate := float64(tb.limit.Rate) / float64(tb.limit.Period.Milliseconds())
+	rest, err := tokenBucket.RunCtx(ctx, tb.cli, []string{tb.prefix + key}, []interface{}{
+		strconv.FormatFloat(rate, 'f', -1, 64), tb.limit.Burst, n,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(rest)
+}
+
+// SlidingWindow 分布式滑动窗口, 按前一个窗口的计数加权估算
+type SlidingWindow struct {
+	cli    *redis.GoRedis
+	prefix string
+	limit  ratelimit.Limit
+}
+
+func NewSlidingWindow(cli *redis.GoRedis, prefix string, limit ratelimit.Limit) (*SlidingWindow, error) {
+	limit, err := limit.Check()
+	if err != nil {
+		return nil, err
+	}
+	if limit.Period < time.Millisecond {
+		return nil, ratelimit.ErrLimit
+	}
+	return &SlidingWindow{cli: cli, prefix: prefix, limit: limit}, nil
+}
+
+func (sw *SlidingWindow) AllowN(ctx context.Context, key string, n int64) (*ratelimit.Result, error) {
+	rest, err := slidingWindow.RunCtx(ctx, sw.cli, []string{sw.prefix + key}, []interface{}{
+		sw.limit.Rate, sw.limit.Period.Milliseconds(), n,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(rest)
+}
@@ -0,0 +1,138 @@
+package redisLock
+
+import (
+	"context"
+	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/election"
+	"github.com/y1015860449/gotoolkit/utils"
+	"log"
+	"time"
+)
+
+var (
+	// KEYS[1] 租约; ARGV[1] 参选者, ARGV[2] 过期毫秒
+	leaseScript = redis.NewScript(`
+		if redis.call('set', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+			return 1
+		end
+		if redis.call('get', KEYS[1]) == ARGV[1] then
+			return redis.call('pexpire', KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+	leaseRenewScript = redis.NewScript(`
+		if redis.call('get', KEYS[1]) == ARGV[1] then
+			return redis.call('pexpire', KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+	leaseReleaseScript = redis.NewScript(`
+		if redis.call('get', KEYS[1]) == ARGV[1] then
+			return redis.call('del', KEYS[1])
+		end
+		return 0
+	`)
+)
+
+// Election 基于租约 key 的选举, 非主节点按 RetryInterval 轮询, 主节点每 TTL/3 续期
+type Election struct {
+	*election.Status
+	cli    *redis.GoRedis
+	key    string
+	opts   election.Options
+	runner election.Runner
+}
+
+func NewElection(cli *redis.GoRedis, key string, opts ...election.Option) *Election {
+	o := election.NewOptions(opts...)
+	if len(o.Value) <= 0 {
+		o.Value = utils.GetUUID()
+	}
+	return &Election{
+		Status: election.NewStatus(o),
+		cli:    cli,
+		key:    key,
+		opts:   o,
+	}
+}
+
+func (e *Election) Campaign(ctx context.Context) error {
+	ctx, ok := e.runner.Start(ctx)
+	if !ok {
+		return election.ErrCampaigning
+	}
+	defer e.runner.Finish()
+	for ctx.Err() == nil {
+		sent := time.Now()
+		ok, err := e.eval(ctx, leaseScript)
+		if err != nil {
+			log.Printf("election lease key(%v) err(%+v)", e.key, err)
+		}
+		if !ok {
+			election.Sleep(ctx, e.opts.RetryInterval)
+			continue
+		}
+		e.Elected()
+		e.hold(ctx, sent)
+		e.Revoked()
+		if ctx.Err() != nil {
+			// ctx 已取消, 让位不受其影响
+			if _, err = e.eval(context.Background(), leaseReleaseScript); err != nil {
+				log.Printf("election release key(%v) err(%+v)", e.key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (e *Election) Resign(ctx context.Context) error {
+	return e.runner.Stop(ctx)
+}
+
+// Leader 当前主节点的标识
+func (e *Election) Leader() (string, error) {
+	return e.cli.Get(e.key)
+}
+
+func (e *Election) eval(ctx context.Context, script *redis.Script) (bool, error) {
+	rest, err := script.RunCtx(ctx, e.cli, []string{e.key}, []interface{}{e.opts.Value, e.opts.TTL.Milliseconds()})
+	if err != nil {
+		return false, err
+	}
+	n, _ := rest.(int64)
+	return n == 1, nil
+}
+
+// hold 定时续期, 租约被抢占或续期失败时返回; 从最后一次成功续期的发送时刻起,
+// 在 TTL 前预留一个续期间隔(远大于时钟漂移)即让位, 保证租约过期前退出主节点
+func (e *Election) hold(ctx context.Context, renewed time.Time) {
+	interval := e.opts.TTL / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	expire := time.NewTimer(e.opts.TTL - interval - time.Since(renewed))
+	defer expire.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expire.C:
+			log.Printf("election key(%v) expired", e.key)
+			return
+		case <-ticker.C:
+			sent := time.Now()
+			ok, err := e.eval(ctx, leaseRenewScript)
+			if err != nil {
+				log.Printf("election renew key(%v) err(%+v)", e.key, err)
+				continue
+			}
+			if !ok {
+				log.Printf("election key(%v) lost", e.key)
+				return
+			}
+			if !expire.Stop() {
+				<-expire.C
+			}
+			expire.Reset(e.opts.TTL - interval - time.Since(sent))
+		}
+	}
+}
@@ -1,20 +1,48 @@
 package redisLock
 
 import (
+	"context"
+	"errors"
 	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
 	"log"
 	"math/rand"
 	"time"
 )
 
+var (
+	keyUnLockScript = redis.NewScript(`
+		if redis.call('get',KEYS[1])==ARGV[1]
+		then 
+			return redis.call('del',KEYS[1])
+		else 
+			return 0
+		end
+	`)
+	keyExpiryScript = redis.NewScript(`
+		if redis.call('get', KEYS[1]) == ARGV[1]
+		then
+			return redis.call('expire', KEYS[1], ARGV[2])
+		else
+			return 0
+		end
+	`)
+)
+
+// rdCli 全局实例, 新代码请使用 NewLocker 绑定实例
 var rdCli *redis.GoRedis
 
 func InitRedis(cli *redis.GoRedis) {
 	rdCli = cli
 }
 
+// KeyLock timeout 为0时一直等待
 func KeyLock(key, value string, expiry int, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
 	for {
+		if timeout > 0 && time.Now().After(deadline) {
+			log.Printf("keyLock timeout! key(%v) value(%v)", key, value)
+			return errors.New("keyLock timeout")
+		}
 		if rest, err := rdCli.SetNxEx(key, value, expiry); err != nil {
 			log.Printf("keyLock SetNxEx err(%+v)", err)
 			return err
@@ -32,15 +60,7 @@ func KeyLock(key, value string, expiry int, timeout time.Duration) error {
 }
 
 func KeyUnLock(key, value string) error {
-	script := `
-		if redis.call('get',KEYS[1])==ARGV[1]
-		then 
-			return redis.call('del',KEYS[1])
-		else 
-			return 0
-		end
-	`
-	rest, err := rdCli.Eval(script, []string{key}, []interface{}{value})
+	rest, err := keyUnLockScript.Run(rdCli, []string{key}, []interface{}{value})
 	if err != nil {
 		log.Printf("keyUnLock Eval err(%+v)", err)
 		return err
@@ -52,28 +72,28 @@ func KeyUnLock(key, value string) error {
 	return nil
 }
 
-// AddKeyExpiry 续时间
-func AddKeyExpiry(key, value string, expiry int) error {
-	select {
-	case <-time.After(time.Second*time.Duration(expiry) - time.Millisecond*50):
-		script := `
-			if redis.call('get', KEYS[1]) == ARGV[1]
-			then
-				return redis.call('setex', KEYS[1], ARGV[2], ARGV[1])
-			else
-				return 'no'
-		`
-		rest, err := rdCli.Eval(script, []string{key}, []interface{}{expiry, value})
-		if err != nil {
-			log.Printf("addKeyExpiry Eval err(%+v)", err)
-			return err
-		} else {
-			if rest.(string) == "OK" {
-				log.Printf("addKeyExpiry success")
-				go AddKeyExpiry(key, value, expiry)
+// AddKeyExpiry 续时间, 每 expiry/3 秒续期一次, 阻塞直到 ctx 取消或 key 已不属于 value
+func AddKeyExpiry(ctx context.Context, key, value string, expiry int) error {
+	interval := time.Second * time.Duration(expiry) / 3
+	if interval <= 0 {
+		return errors.New("param is err")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			rest, err := keyExpiryScript.Run(rdCli, []string{key}, []interface{}{value, expiry})
+			if err != nil {
+				log.Printf("addKeyExpiry Eval err(%+v)", err)
+				return err
+			}
+			if n, _ := rest.(int64); n != 1 {
+				log.Printf("addKeyExpiry lost! key(%v) value(%v)", key, value)
+				return errors.New("keyLock lost")
 			}
 		}
-
 	}
-	return nil
 }
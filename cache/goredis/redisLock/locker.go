@@ -0,0 +1,271 @@
+package redisLock
+
+import (
+	"context"
+	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/locker"
+	"github.com/y1015860449/gotoolkit/utils"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// KEYS[1] 锁, KEYS[2] 递增的fencing计数; ARGV[1] 持有者, ARGV[2] 过期毫秒, ARGV[3] 为1时生成 fencing token
+	// 返回 fencing token(不生成时为1), 被其他持有者占用时返回0
+	lockScript = redis.NewScript(`
+		if redis.call('exists', KEYS[1]) == 0 then
+			local token = 1
+			if ARGV[3] == '1' then
+				token = redis.call('incr', KEYS[2])
+			end
+			redis.call('hset', KEYS[1], 'owner', ARGV[1], 'count', 1, 'token', token)
+			redis.call('pexpire', KEYS[1], ARGV[2])
+			return token
+		end
+		if redis.call('hget', KEYS[1], 'owner') == ARGV[1] then
+			redis.call('hincrby', KEYS[1], 'count', 1)
+			redis.call('pexpire', KEYS[1], ARGV[2])
+			return tonumber(redis.call('hget', KEYS[1], 'token'))
+		end
+		return 0
+	`)
+	renewScript = redis.NewScript(`
+		if redis.call('hget', KEYS[1], 'owner') == ARGV[1] then
+			return redis.call('pexpire', KEYS[1], ARGV[2])
+		end
+		return 0
+	`)
+	// 返回剩余重入次数, 不属于自己时返回-1
+	unlockScript = redis.NewScript(`
+		if redis.call('hget', KEYS[1], 'owner') ~= ARGV[1] then
+			return -1
+		end
+		local count = redis.call('hincrby', KEYS[1], 'count', -1)
+		if count > 0 then
+			redis.call('pexpire', KEYS[1], ARGV[2])
+			return count
+		end
+		redis.call('del', KEYS[1])
+		return 0
+	`)
+	// 强制释放, 用于 Redlock 加锁失败时回滚
+	releaseScript = redis.NewScript(`
+		if redis.call('hget', KEYS[1], 'owner') == ARGV[1] then
+			return redis.call('del', KEYS[1])
+		end
+		return 0
+	`)
+)
+
+// Locker 可重入分布式锁, 持有期间 watchdog 自动续期;
+// 单节点时每次获取返回递增的 fencing token. 多个独立节点时为 Redlock, 多数节点成功才算获取,
+// 各节点的计数相互独立, 只到达少数节点的失败尝试也会递增计数, 无法保证单调, 因此不提供 fencing token
+type Locker struct {
+	clis     []*redis.GoRedis
+	lockKey  string
+	fenceKey string
+	owner    string
+	opts     locker.Options
+	mtx      sync.Mutex
+	count    int
+	token    int64
+	stop     chan struct{}
+}
+
+// NewLocker 单节点锁. key 不含hash tag时自动加上 {key}, 保证cluster下两个key在同一slot
+func NewLocker(cli *redis.GoRedis, key string, opts ...locker.Option) *Locker {
+	return NewRedLocker([]*redis.GoRedis{cli}, key, opts...)
+}
+
+// NewRedLocker Redlock, clis 为相互独立的redis节点, 建议奇数个
+func NewRedLocker(clis []*redis.GoRedis, key string, opts ...locker.Option) *Locker {
+	if !strings.Contains(key, "{") {
+		key = "{" + key + "}"
+	}
+	l := &Locker{
+		clis:     clis,
+		lockKey:  key,
+		fenceKey: key + ":fence",
+		opts:     locker.NewOptions(opts...),
+	}
+	l.owner = l.opts.Owner
+	if len(l.owner) <= 0 {
+		l.owner = utils.GetUUID()
+	}
+	return l
+}
+
+func (l *Locker) quorum() int {
+	return len(l.clis)/2 + 1
+}
+
+// Token 当前持有锁的 fencing token, 写入下游存储时携带, 用于拒绝过期持有者的写入;
+// 多节点 Redlock 时恒为0
+func (l *Locker) Token() int64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.token
+}
+
+func (l *Locker) Lock(ctx context.Context) error {
+	ctx, cancel := locker.WithTimeout(ctx, l.opts)
+	defer cancel()
+	for {
+		ok, err := l.TryLock(ctx)
+		if err != nil || ok {
+			return err
+		}
+		wait := l.opts.RetryInterval + time.Duration(rand.Int63n(int64(l.opts.RetryInterval)))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (l *Locker) TryLock(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	ttl := l.opts.TTL.Milliseconds()
+	begin := time.Now()
+	var success int
+	var token int64
+	var lastErr error
+	fencing := 0
+	if len(l.clis) == 1 {
+		fencing = 1
+	}
+	for _, cli := range l.clis {
+		rest, err := lockScript.RunCtx(ctx, cli, []string{l.lockKey, l.fenceKey}, []interface{}{l.owner, ttl, fencing})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if n, _ := rest.(int64); n > 0 {
+			success++
+			if fencing == 1 {
+				token = n
+			}
+		}
+	}
+	// 扣除加锁耗时和时钟漂移后仍有剩余有效期才算成功
+	drift := l.opts.TTL/100 + 2*time.Millisecond
+	if success < l.quorum() || time.Since(begin)+drift >= l.opts.TTL {
+		if l.count <= 0 {
+			l.release()
+		}
+		if success == 0 && lastErr != nil {
+			return false, lastErr
+		}
+		return false, nil
+	}
+	l.count++
+	l.token = token
+	if l.stop == nil {
+		l.stop = make(chan struct{})
+		go l.watchdog(l.stop)
+	}
+	return true, nil
+}
+
+// release 加锁失败时释放已获得的节点, 不受调用方 ctx 取消影响
+func (l *Locker) release() {
+	for _, cli := range l.clis {
+		_, _ = releaseScript.RunCtx(context.Background(), cli, []string{l.lockKey}, []interface{}{l.owner})
+	}
+}
+
+// watchdog 每 TTL/3 续期一次, 多数节点续期失败视为锁丢失; 释放锁时取消进行中的续期
+func (l *Locker) watchdog(stop chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	ticker := time.NewTicker(l.opts.TTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var success, failed int
+			for _, cli := range l.clis {
+				rest, err := renewScript.RunCtx(ctx, cli, []string{l.lockKey}, []interface{}{l.owner, l.opts.TTL.Milliseconds()})
+				if err == context.Canceled {
+					return
+				}
+				if err != nil {
+					log.Printf("locker renew key(%v) err(%+v)", l.lockKey, err)
+					continue
+				}
+				if n, _ := rest.(int64); n == 1 {
+					success++
+				} else {
+					failed++
+				}
+			}
+			if success >= l.quorum() || failed < l.quorum() {
+				continue
+			}
+			log.Printf("locker renew key(%v) lost", l.lockKey)
+			l.mtx.Lock()
+			if l.stop == stop {
+				l.count, l.token, l.stop = 0, 0, nil
+			}
+			l.mtx.Unlock()
+			return
+		}
+	}
+}
+
+// Unlock 重入时只减少计数, 计数归零才真正释放; ctx 已取消时不改变持有状态
+func (l *Locker) Unlock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.count <= 0 {
+		return locker.ErrNotLocked
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	l.count--
+	var owned int
+	var lastErr error
+	for _, cli := range l.clis {
+		rest, err := unlockScript.RunCtx(ctx, cli, []string{l.lockKey}, []interface{}{l.owner, l.opts.TTL.Milliseconds()})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if n, _ := rest.(int64); n >= 0 {
+			owned++
+		}
+	}
+	if l.count > 0 {
+		return lastErr
+	}
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.token = 0
+	if owned < l.quorum() {
+		if lastErr != nil {
+			return lastErr
+		}
+		return locker.ErrLockLost
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package redisLock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/utils"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// WorkerLeaser 以租约 key prefix:<id> 占用 worker id, 每 TTL/3 续期, 实现 idMaker.WorkerLeaser
+type WorkerLeaser struct {
+	cli    *redis.GoRedis
+	prefix string
+	ttl    time.Duration
+	owner  string
+	mtx    sync.Mutex
+	key    string
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewWorkerLeaser ttl 为租约时间, 实例宕机后该时间内 id 不会被复用
+func NewWorkerLeaser(cli *redis.GoRedis, prefix string, ttl time.Duration) *WorkerLeaser {
+	if ttl <= 0 {
+		ttl = 10 * time.Second
+	}
+	return &WorkerLeaser{
+		cli:    cli,
+		prefix: prefix,
+		ttl:    ttl,
+		owner:  utils.GetUUID(),
+	}
+}
+
+func (w *WorkerLeaser) eval(ctx context.Context, script *redis.Script, key string) (bool, error) {
+	rest, err := script.RunCtx(ctx, w.cli, []string{key}, []interface{}{w.owner, w.ttl.Milliseconds()})
+	if err != nil {
+		return false, err
+	}
+	n, _ := rest.(int64)
+	return n == 1, nil
+}
+
+func (w *WorkerLeaser) Acquire(ctx context.Context, max int64) (int64, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if len(w.key) > 0 {
+		return 0, errors.New("worker id already acquired")
+	}
+	start := rand.Int63n(max)
+	for i := int64(0); i < max; i++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		id := (start + i) % max
+		key := fmt.Sprintf("%s:%d", w.prefix, id)
+		sent := time.Now()
+		ok, err := w.eval(ctx, leaseScript, key)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			continue
+		}
+		// 每次租用新建 done, Release 后可以再次 Acquire
+		w.key, w.stop, w.done = key, make(chan struct{}), make(chan struct{})
+		go w.renew(key, w.stop, w.done, sent)
+		return id, nil
+	}
+	return 0, errors.New("no free worker id")
+}
+
+// renew 租约被抢占或续期失败时关闭 done; 从最后一次成功续期的发送时刻起,
+// 在 TTL 前预留一个续期间隔即判定丢失, 保证 key 过期前停止使用该 id
+func (w *WorkerLeaser) renew(key string, stop, done chan struct{}, renewed time.Time) {
+	defer close(done)
+	interval := w.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	expire := time.NewTimer(w.ttl - interval - time.Since(renewed))
+	defer expire.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-expire.C:
+			log.Printf("worker lease key(%v) expired", key)
+			return
+		case <-ticker.C:
+			sent := time.Now()
+			ok, err := w.eval(context.Background(), leaseRenewScript, key)
+			if err != nil {
+				log.Printf("worker lease renew key(%v) err(%+v)", key, err)
+				continue
+			}
+			if !ok {
+				log.Printf("worker lease key(%v) lost", key)
+				return
+			}
+			if !expire.Stop() {
+				<-expire.C
+			}
+			expire.Reset(w.ttl - interval - time.Since(sent))
+		}
+	}
+}
+
+// Done 当前租约丢失或释放时关闭, Acquire 之前返回 nil
+func (w *WorkerLeaser) Done() <-chan struct{} {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.done
+}
+
+func (w *WorkerLeaser) Release(ctx context.Context) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if len(w.key) <= 0 {
+		return nil
+	}
+	close(w.stop)
+	key := w.key
+	w.key, w.stop = "", nil
+	_, err := w.eval(ctx, leaseReleaseScript, key)
+	return err
+}
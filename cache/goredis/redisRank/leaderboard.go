@@ -0,0 +1,181 @@
+// Package redisRank 基于有序集合的排行榜
+package redisRank
+
+import (
+	"errors"
+	goredis "github.com/go-redis/redis"
+	"github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"strconv"
+)
+
+// KEYS[1] 排行榜; ARGV[1] 成员, ARGV[2] 前后各取的条数, ARGV[3] 为1时分数低的排前面
+// 返回 {起始排名(从0开始), 成员, 分数, 成员, 分数...}, 成员不存在时返回空
+var aroundScript = redis.NewScript(`
+	local asc = ARGV[3] == '1'
+	local rank
+	if asc then
+		rank = redis.call('zrank', KEYS[1], ARGV[1])
+	else
+		rank = redis.call('zrevrank', KEYS[1], ARGV[1])
+	end
+	if not rank then
+		return {}
+	end
+	local n = tonumber(ARGV[2])
+	local start = math.max(0, rank - n)
+	local list
+	if asc then
+		list = redis.call('zrange', KEYS[1], start, rank + n, 'withscores')
+	else
+		list = redis.call('zrevrange', KEYS[1], start, rank + n, 'withscores')
+	end
+	table.insert(list, 1, start)
+	return list
+`)
+
+type Entry struct {
+	Member string
+	Score  int64
+	Rank   int64 // 从1开始
+}
+
+// Leaderboard 分数相同时升序榜按成员字典序排列, 降序榜按成员字典序倒序排列
+type Leaderboard struct {
+	cli *redis.GoRedis
+	key string
+	asc bool
+}
+
+// NewLeaderboard asc 为 true 时分数低的排前面, 例如耗时榜
+func NewLeaderboard(cli *redis.GoRedis, key string, asc bool) *Leaderboard {
+	return &Leaderboard{cli: cli, key: key, asc: asc}
+}
+
+// Set 设置分数
+func (l *Leaderboard) Set(member string, score int64) error {
+	_, err := l.cli.ZAdd(l.key, member, score)
+	return err
+}
+
+// Incr 增加分数, 返回新分数
+func (l *Leaderboard) Incr(member string, delta int64) (int64, error) {
+	return l.cli.ZIncrBy(l.key, member, delta)
+}
+
+func (l *Leaderboard) Remove(members ...string) error {
+	fields := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		fields = append(fields, member)
+	}
+	_, err := l.cli.ZRem(l.key, fields...)
+	return err
+}
+
+// Count 上榜人数
+func (l *Leaderboard) Count() (int64, error) {
+	return l.cli.ZCard(l.key)
+}
+
+// Get 成员的排名和分数, 不在榜上时返回 nil
+func (l *Leaderboard) Get(member string) (*Entry, error) {
+	var rank *goredis.IntCmd
+	var score *goredis.FloatCmd
+	_, err := l.cli.Pipelined(func(p goredis.Pipeliner) error {
+		if l.asc {
+			rank = p.ZRank(l.key, member)
+		} else {
+			rank = p.ZRevRank(l.key, member)
+		}
+		score = p.ZScore(l.key, member)
+		return nil
+	})
+	if err == goredis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Member: member, Score: int64(score.Val()), Rank: rank.Val() + 1}, nil
+}
+
+// Page 分页查询, page 从1开始
+func (l *Leaderboard) Page(page, size int64) ([]Entry, error) {
+	if page <= 0 || size <= 0 {
+		return nil, errors.New("param is err")
+	}
+	return l.Range((page-1)*size, page*size-1)
+}
+
+// Top 前 n 名
+func (l *Leaderboard) Top(n int64) ([]Entry, error) {
+	return l.Page(1, n)
+}
+
+// Range 按排名区间查询, start 和 stop 从0开始且包含 stop
+func (l *Leaderboard) Range(start, stop int64) ([]Entry, error) {
+	var cmd *goredis.ZSliceCmd
+	_, err := l.cli.Pipelined(func(p goredis.Pipeliner) error {
+		if l.asc {
+			cmd = p.ZRangeWithScores(l.key, start, stop)
+		} else {
+			cmd = p.ZRevRangeWithScores(l.key, start, stop)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Entry, 0, len(cmd.Val()))
+	for i, z := range cmd.Val() {
+		member, _ := z.Member.(string)
+		list = append(list, Entry{Member: member, Score: int64(z.Score), Rank: start + int64(i) + 1})
+	}
+	return list, nil
+}
+
+// Around 成员及其前后各 n 名, 成员不在榜上时返回空
+func (l *Leaderboard) Around(member string, n int64) ([]Entry, error) {
+	if n < 0 {
+		return nil, errors.New("param is err")
+	}
+	asc := 0
+	if l.asc {
+		asc = 1
+	}
+	rest, err := aroundScript.Run(l.cli, []string{l.key}, []interface{}{member, n, asc})
+	if err != nil {
+		return nil, err
+	}
+	return parseAround(rest)
+}
+
+// parseAround 解析 aroundScript 的返回, 分数由 redis 以字符串返回
+func parseAround(rest interface{}) ([]Entry, error) {
+	vals, ok := rest.([]interface{})
+	if !ok {
+		return nil, errors.New("leaderboard: unexpected script result")
+	}
+	if len(vals) <= 0 {
+		return nil, nil
+	}
+	start, ok := vals[0].(int64)
+	if !ok || len(vals)%2 != 1 {
+		return nil, errors.New("leaderboard: unexpected script result")
+	}
+	list := make([]Entry, 0, len(vals)/2)
+	for i := 1; i+1 < len(vals); i += 2 {
+		m, _ := vals[i].(string)
+		s, _ := vals[i+1].(string)
+		score, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Entry{Member: m, Score: int64(score), Rank: start + int64(len(list)) + 1})
+	}
+	return list, nil
+}
+
+// Clear 删除排行榜
+func (l *Leaderboard) Clear() error {
+	return l.cli.Del([]string{l.key})
+}
@@ -0,0 +1,37 @@
+package redisRank
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAround(t *testing.T) {
+	list, err := parseAround([]interface{}{int64(2), "a", "30", "b", "20.5", "c", "-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Member: "a", Score: 30, Rank: 3},
+		{Member: "b", Score: 20, Rank: 4},
+		{Member: "c", Score: -1, Rank: 5},
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Fatalf("got %+v, want %+v", list, want)
+	}
+
+	// 成员不在榜上
+	if list, err = parseAround([]interface{}{}); err != nil || list != nil {
+		t.Fatalf("got %+v err(%v)", list, err)
+	}
+
+	for _, rest := range []interface{}{
+		"x",
+		[]interface{}{"0", "a", "1"},
+		[]interface{}{int64(0), "a"},
+		[]interface{}{int64(0), "a", "x"},
+	} {
+		if _, err = parseAround(rest); err == nil {
+			t.Fatalf("want err for %+v", rest)
+		}
+	}
+}
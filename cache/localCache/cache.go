@@ -0,0 +1,404 @@
+// Package localCache 进程内分片缓存, 支持 LRU/LFU 淘汰、过期时间、容量成本、淘汰回调和命中统计
+package localCache
+
+import (
+	"errors"
+	"fmt"
+	"github.com/y1015860449/gotoolkit/utils"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Policy int
+
+const (
+	LRU Policy = iota // 淘汰最久未访问的
+	LFU               // 淘汰访问次数最少的
+)
+
+// EvictReason 条目被移除的原因
+type EvictReason int
+
+const (
+	Expired  EvictReason = iota // 过期
+	Capacity                    // 超过容量被淘汰
+	Deleted                     // 主动删除或清空
+	Replaced                    // 被同一 key 的新值覆盖
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Capacity:
+		return "capacity"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	}
+	return "unknown"
+}
+
+type Config struct {
+	Shards        int           // 分片数, 向上取整为2的幂, 默认16
+	MaxCost       int64         // 总容量, 平均分到各分片, 不指定成本时每个条目成本为1, 默认10000
+	Policy        Policy        // 淘汰策略, 默认 LRU
+	TTL           time.Duration // 默认过期时间, 为0不过期
+	CleanInterval time.Duration // 清理过期条目的间隔, 默认1分钟, 小于0不主动清理只在访问时检查
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Shards:        16,
+		MaxCost:       10000,
+		Policy:        LRU,
+		CleanInterval: time.Minute,
+	}
+}
+
+// Stats 命中统计
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Sets        uint64
+	Rejects     uint64 // 成本超过分片容量被拒绝的写入
+	Evictions   uint64 // 容量淘汰
+	Expirations uint64 // 过期移除
+}
+
+func (s Stats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type Cache[K comparable, V any] struct {
+	conf    Config
+	shards  []*shard[K, V]
+	mask    uint64
+	onEvict func(key K, value V, reason EvictReason)
+	stats   Stats
+	now     func() int64
+	stop    chan struct{}
+	once    sync.Once
+}
+
+type shard[K comparable, V any] struct {
+	mtx     sync.Mutex
+	items   map[K]*item[K, V]
+	policy  policy[K, V]
+	cost    int64
+	maxCost int64
+}
+
+type evicted[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictReason
+}
+
+// New onEvict 在条目被移除后调用, 不持有锁, 可为 nil
+func New[K comparable, V any](conf *Config, onEvict func(key K, value V, reason EvictReason)) (*Cache[K, V], error) {
+	if conf == nil {
+		conf = DefaultConfig()
+	}
+	if conf.Shards <= 0 {
+		conf.Shards = 16
+	}
+	if conf.CleanInterval == 0 {
+		conf.CleanInterval = time.Minute
+	}
+	n := 1
+	for n < conf.Shards {
+		n <<= 1
+	}
+	if conf.MaxCost < int64(n) || conf.TTL < 0 || (conf.Policy != LRU && conf.Policy != LFU) {
+		return nil, errors.New("config is err")
+	}
+	c := &Cache[K, V]{
+		conf:    *conf,
+		shards:  make([]*shard[K, V], n),
+		mask:    uint64(n - 1),
+		onEvict: onEvict,
+		now:     func() int64 { return time.Now().UnixNano() },
+		stop:    make(chan struct{}),
+	}
+	for i := range c.shards {
+		c.shards[i] = &shard[K, V]{
+			items:   make(map[K]*item[K, V]),
+			policy:  newPolicy[K, V](conf.Policy),
+			maxCost: conf.MaxCost / int64(n),
+		}
+	}
+	if conf.CleanInterval > 0 {
+		go c.cleaner()
+	}
+	return c, nil
+}
+
+func hashKey(key interface{}) uint64 {
+	switch k := key.(type) {
+	case string:
+		return utils.Hash64([]byte(k))
+	case int:
+		return mix(uint64(k))
+	case int32:
+		return mix(uint64(k))
+	case int64:
+		return mix(uint64(k))
+	case uint:
+		return mix(uint64(k))
+	case uint32:
+		return mix(uint64(k))
+	case uint64:
+		return mix(k)
+	case []byte:
+		return utils.Hash64(k)
+	case fmt.Stringer:
+		return utils.Hash64([]byte(k.String()))
+	}
+	return utils.Hash64([]byte(fmt.Sprintf("%#v", key)))
+}
+
+// mix 整数 key 打散到各分片
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+func (c *Cache[K, V]) shard(key K) *shard[K, V] {
+	return c.shards[hashKey(key)&c.mask]
+}
+
+func (c *Cache[K, V]) expired(it *item[K, V], now int64) bool {
+	return it.expireAt > 0 && now >= it.expireAt
+}
+
+func (c *Cache[K, V]) notify(list []evicted[K, V]) {
+	for _, e := range list {
+		switch e.reason {
+		case Capacity:
+			atomic.AddUint64(&c.stats.Evictions, 1)
+		case Expired:
+			atomic.AddUint64(&c.stats.Expirations, 1)
+		}
+		if c.onEvict != nil {
+			c.onEvict(e.key, e.value, e.reason)
+		}
+	}
+}
+
+// removeLocked 调用方持有分片锁
+func (s *shard[K, V]) removeLocked(it *item[K, V]) {
+	s.policy.remove(it)
+	delete(s.items, it.key)
+	s.cost -= it.cost
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	s := c.shard(key)
+	s.mtx.Lock()
+	it, ok := s.items[key]
+	if ok && c.expired(it, c.now()) {
+		s.removeLocked(it)
+		s.mtx.Unlock()
+		atomic.AddUint64(&c.stats.Misses, 1)
+		c.notify([]evicted[K, V]{{it.key, it.value, Expired}})
+		var zero V
+		return zero, false
+	}
+	if !ok {
+		s.mtx.Unlock()
+		atomic.AddUint64(&c.stats.Misses, 1)
+		var zero V
+		return zero, false
+	}
+	s.policy.touch(it)
+	value := it.value
+	s.mtx.Unlock()
+	atomic.AddUint64(&c.stats.Hits, 1)
+	return value, true
+}
+
+// Set 成本为1, 使用默认过期时间
+func (c *Cache[K, V]) Set(key K, value V) bool {
+	return c.SetWithCost(key, value, 1, c.conf.TTL)
+}
+
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) bool {
+	return c.SetWithCost(key, value, 1, ttl)
+}
+
+// SetWithCost 成本超过单个分片容量时拒绝写入返回 false, ttl 为0不过期
+func (c *Cache[K, V]) SetWithCost(key K, value V, cost int64, ttl time.Duration) bool {
+	if cost <= 0 {
+		cost = 1
+	}
+	s := c.shard(key)
+	if cost > s.maxCost {
+		atomic.AddUint64(&c.stats.Rejects, 1)
+		return false
+	}
+	now := c.now()
+	var expireAt int64
+	if ttl > 0 {
+		expireAt = now + int64(ttl)
+	}
+	var list []evicted[K, V]
+
+	s.mtx.Lock()
+	if old, ok := s.items[key]; ok {
+		s.removeLocked(old)
+		reason := Replaced
+		if c.expired(old, now) {
+			reason = Expired
+		}
+		list = append(list, evicted[K, V]{old.key, old.value, reason})
+	}
+	it := &item[K, V]{key: key, value: value, cost: cost, expireAt: expireAt}
+	// 按策略淘汰直到容量足够
+	for s.cost+cost > s.maxCost {
+		victim := s.policy.victim()
+		if victim == nil {
+			break
+		}
+		s.removeLocked(victim)
+		reason := Capacity
+		if c.expired(victim, now) {
+			reason = Expired
+		}
+		list = append(list, evicted[K, V]{victim.key, victim.value, reason})
+	}
+	s.items[key] = it
+	s.policy.add(it)
+	s.cost += cost
+	s.mtx.Unlock()
+
+	atomic.AddUint64(&c.stats.Sets, 1)
+	c.notify(list)
+	return true
+}
+
+func (c *Cache[K, V]) Delete(key K) bool {
+	s := c.shard(key)
+	s.mtx.Lock()
+	it, ok := s.items[key]
+	if ok {
+		s.removeLocked(it)
+	}
+	s.mtx.Unlock()
+	if ok {
+		c.notify([]evicted[K, V]{{it.key, it.value, Deleted}})
+	}
+	return ok
+}
+
+// Len 条目数, 包含已过期未清理的
+func (c *Cache[K, V]) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mtx.Lock()
+		n += len(s.items)
+		s.mtx.Unlock()
+	}
+	return n
+}
+
+// Cost 已使用的容量
+func (c *Cache[K, V]) Cost() int64 {
+	var cost int64
+	for _, s := range c.shards {
+		s.mtx.Lock()
+		cost += s.cost
+		s.mtx.Unlock()
+	}
+	return cost
+}
+
+// Range 遍历未过期的条目, fn 返回 false 时停止, 遍历期间不持有锁
+func (c *Cache[K, V]) Range(fn func(key K, value V) bool) {
+	now := c.now()
+	for _, s := range c.shards {
+		s.mtx.Lock()
+		list := make([]evicted[K, V], 0, len(s.items))
+		for _, it := range s.items {
+			if !c.expired(it, now) {
+				list = append(list, evicted[K, V]{key: it.key, value: it.value})
+			}
+		}
+		s.mtx.Unlock()
+		for _, e := range list {
+			if !fn(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Clear 清空缓存, 每个条目以 Deleted 回调
+func (c *Cache[K, V]) Clear() {
+	for _, s := range c.shards {
+		s.mtx.Lock()
+		list := make([]evicted[K, V], 0, len(s.items))
+		for _, it := range s.items {
+			list = append(list, evicted[K, V]{it.key, it.value, Deleted})
+		}
+		s.items = make(map[K]*item[K, V])
+		s.policy = newPolicy[K, V](c.conf.Policy)
+		s.cost = 0
+		s.mtx.Unlock()
+		c.notify(list)
+	}
+}
+
+func (c *Cache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:        atomic.LoadUint64(&c.stats.Hits),
+		Misses:      atomic.LoadUint64(&c.stats.Misses),
+		Sets:        atomic.LoadUint64(&c.stats.Sets),
+		Rejects:     atomic.LoadUint64(&c.stats.Rejects),
+		Evictions:   atomic.LoadUint64(&c.stats.Evictions),
+		Expirations: atomic.LoadUint64(&c.stats.Expirations),
+	}
+}
+
+func (c *Cache[K, V]) cleanExpired() {
+	now := c.now()
+	for _, s := range c.shards {
+		var list []evicted[K, V]
+		s.mtx.Lock()
+		for _, it := range s.items {
+			if c.expired(it, now) {
+				s.removeLocked(it)
+				list = append(list, evicted[K, V]{it.key, it.value, Expired})
+			}
+		}
+		s.mtx.Unlock()
+		c.notify(list)
+	}
+}
+
+func (c *Cache[K, V]) cleaner() {
+	ticker := time.NewTicker(c.conf.CleanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.cleanExpired()
+		}
+	}
+}
+
+// Close 停止后台清理, 不清空缓存; CleanInterval 大于0时不再使用需调用, 否则清理协程不会退出
+func (c *Cache[K, V]) Close() {
+	c.once.Do(func() {
+		close(c.stop)
+	})
+}
@@ -0,0 +1,134 @@
+package localCache
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T, policy Policy, maxCost int64) (*Cache[string, int], *[]string) {
+	var evicted []string
+	c, err := New[string, int](&Config{Shards: 1, MaxCost: maxCost, Policy: policy, CleanInterval: -1},
+		func(key string, value int, reason EvictReason) {
+			evicted = append(evicted, key+":"+reason.String())
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, &evicted
+}
+
+func TestLRU(t *testing.T) {
+	c, evicted := newTestCache(t, LRU, 3)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")
+	c.Set("d", 4)
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("b should be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("a = %d %v", v, ok)
+	}
+	c.Set("a", 10)
+	c.Delete("c")
+	want := []string{"b:capacity", "a:replaced", "c:deleted"}
+	if len(*evicted) != len(want) {
+		t.Fatalf("evicted %v", *evicted)
+	}
+	for i := range want {
+		if (*evicted)[i] != want[i] {
+			t.Fatalf("evicted %v", *evicted)
+		}
+	}
+	st := c.Stats()
+	if st.Hits != 2 || st.Misses != 1 || st.Evictions != 1 {
+		t.Fatalf("stats %+v", st)
+	}
+}
+
+func TestLFU(t *testing.T) {
+	c, evicted := newTestCache(t, LFU, 3)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	for i := 0; i < 3; i++ {
+		c.Get("a")
+		c.Get("c")
+	}
+	c.Get("b")
+	c.Get("c")
+	c.Set("d", 4) // b 访问次数最少
+	if len(*evicted) != 1 || (*evicted)[0] != "b:capacity" {
+		t.Fatalf("evicted %v", *evicted)
+	}
+	c.Set("e", 5) // d 和 e 之前 d 次数最少
+	if len(*evicted) != 2 || (*evicted)[1] != "d:capacity" {
+		t.Fatalf("evicted %v", *evicted)
+	}
+}
+
+func TestTTLAndCost(t *testing.T) {
+	c, evicted := newTestCache(t, LRU, 10)
+	now := time.Now().UnixNano()
+	c.now = func() int64 { return now }
+	c.SetWithTTL("a", 1, time.Second)
+	c.SetWithCost("big", 2, 8, 0)
+	if c.Cost() != 9 {
+		t.Fatalf("cost %d", c.Cost())
+	}
+	if c.SetWithCost("huge", 3, 11, 0) {
+		t.Fatal("cost over capacity should be rejected")
+	}
+	now += int64(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Fatal("a should be expired")
+	}
+	c.SetWithTTL("b", 1, time.Second)
+	now += int64(2 * time.Second)
+	c.cleanExpired()
+	if c.Len() != 1 || c.Cost() != 8 {
+		t.Fatalf("len %d cost %d", c.Len(), c.Cost())
+	}
+	if len(*evicted) != 2 || (*evicted)[0] != "a:expired" || (*evicted)[1] != "b:expired" {
+		t.Fatalf("evicted %v", *evicted)
+	}
+	if st := c.Stats(); st.Expirations != 2 || st.Rejects != 1 {
+		t.Fatalf("stats %+v", st)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	for _, policy := range []Policy{LRU, LFU} {
+		c, err := New[int, string](&Config{MaxCost: 256, Policy: policy}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					key := (g*7919 + i) % 1000
+					if _, ok := c.Get(key); !ok {
+						c.Set(key, strconv.Itoa(key))
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		if c.Cost() > 256 || int64(c.Len()) != c.Cost() {
+			t.Fatalf("len %d cost %d", c.Len(), c.Cost())
+		}
+		c.Range(func(key int, value string) bool {
+			if value != strconv.Itoa(key) {
+				t.Fatalf("%d = %s", key, value)
+			}
+			return true
+		})
+		c.Close()
+	}
+}
@@ -0,0 +1,127 @@
+package localCache
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+type item[K comparable, V any] struct {
+	key      K
+	value    V
+	cost     int64
+	expireAt int64 // 纳秒, 0为不过期
+	freq     uint32
+	access   uint64
+	elem     *list.Element
+	index    int
+}
+
+// policy 淘汰策略, 调用方持有分片锁
+type policy[K comparable, V any] interface {
+	add(it *item[K, V])
+	touch(it *item[K, V])
+	remove(it *item[K, V])
+	// victim 返回下一个淘汰的条目, 为空返回 nil
+	victim() *item[K, V]
+}
+
+func newPolicy[K comparable, V any](p Policy) policy[K, V] {
+	if p == LFU {
+		return &lfu[K, V]{}
+	}
+	return &lru[K, V]{ll: list.New()}
+}
+
+type lru[K comparable, V any] struct {
+	ll *list.List
+}
+
+func (l *lru[K, V]) add(it *item[K, V]) {
+	it.elem = l.ll.PushFront(it)
+}
+
+func (l *lru[K, V]) touch(it *item[K, V]) {
+	l.ll.MoveToFront(it.elem)
+}
+
+func (l *lru[K, V]) remove(it *item[K, V]) {
+	l.ll.Remove(it.elem)
+	it.elem = nil
+}
+
+func (l *lru[K, V]) victim() *item[K, V] {
+	if e := l.ll.Back(); e != nil {
+		return e.Value.(*item[K, V])
+	}
+	return nil
+}
+
+// lfu 按访问次数淘汰, 次数相同时淘汰最久未访问的
+// 次数达到上限时全部减半, 避免过去的热点长期占用
+type lfu[K comparable, V any] struct {
+	items []*item[K, V]
+	tick  uint64
+}
+
+const maxFreq = 1 << 16
+
+func (l *lfu[K, V]) Len() int { return len(l.items) }
+
+func (l *lfu[K, V]) Less(i, j int) bool {
+	if l.items[i].freq != l.items[j].freq {
+		return l.items[i].freq < l.items[j].freq
+	}
+	return l.items[i].access < l.items[j].access
+}
+
+func (l *lfu[K, V]) Swap(i, j int) {
+	l.items[i], l.items[j] = l.items[j], l.items[i]
+	l.items[i].index = i
+	l.items[j].index = j
+}
+
+func (l *lfu[K, V]) Push(x interface{}) {
+	it := x.(*item[K, V])
+	it.index = len(l.items)
+	l.items = append(l.items, it)
+}
+
+func (l *lfu[K, V]) Pop() interface{} {
+	n := len(l.items)
+	it := l.items[n-1]
+	l.items[n-1] = nil
+	l.items = l.items[:n-1]
+	it.index = -1
+	return it
+}
+
+func (l *lfu[K, V]) add(it *item[K, V]) {
+	l.tick++
+	it.freq, it.access = 1, l.tick
+	heap.Push(l, it)
+}
+
+func (l *lfu[K, V]) touch(it *item[K, V]) {
+	l.tick++
+	it.freq++
+	it.access = l.tick
+	if it.freq >= maxFreq {
+		for _, v := range l.items {
+			v.freq = v.freq/2 + 1
+		}
+		heap.Init(l)
+		return
+	}
+	heap.Fix(l, it.index)
+}
+
+func (l *lfu[K, V]) remove(it *item[K, V]) {
+	heap.Remove(l, it.index)
+}
+
+func (l *lfu[K, V]) victim() *item[K, V] {
+	if len(l.items) > 0 {
+		return l.items[0]
+	}
+	return nil
+}
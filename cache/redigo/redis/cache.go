@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/y1015860449/gotoolkit/cache"
+)
+
+// redigoCache 按 cache.Cache 的语义包装 Redigo, 只有 Get 的行为不同
+type redigoCache struct {
+	*Redigo
+}
+
+// Cache 返回 cache.Cache 实现, Get 在 key 不存在时返回空字符串而不是 redis.ErrNil
+func (hy *Redigo) Cache() cache.Cache {
+	return &redigoCache{Redigo: hy}
+}
+
+func (c *redigoCache) Get(key string) (string, error) {
+	value, err := c.Redigo.Get(key)
+	if err != nil && err != redis.ErrNil {
+		return "", err
+	}
+	return value, nil
+}
@@ -0,0 +1,53 @@
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// Pipeline 在同一个连接上批量发送命令, 减少往返
+type Pipeline struct {
+	conn redis.Conn
+	n    int
+}
+
+func (p *Pipeline) Send(cmd string, args ...interface{}) error {
+	if err := p.conn.Send(cmd, args...); err != nil {
+		return err
+	}
+	p.n++
+	return nil
+}
+
+// Pipelined 依次返回每条命令的结果, 字符串统一为 string; 返回第一个出错命令的错误
+func (hy *Redigo) Pipelined(fn func(p *Pipeline) error) ([]interface{}, error) {
+	conn := hy.pool.Get()
+	defer conn.Close()
+	p := &Pipeline{conn: conn}
+	if err := fn(p); err != nil {
+		return nil, err
+	}
+	if p.n <= 0 {
+		return nil, nil
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, err
+	}
+	var first error
+	list := make([]interface{}, 0, p.n)
+	for i := 0; i < p.n; i++ {
+		reply, err := conn.Receive()
+		if err != nil {
+			if _, ok := err.(redis.Error); !ok {
+				// 连接错误, 后续回复无法读取
+				return list, err
+			}
+			if first == nil {
+				first = err
+			}
+			list = append(list, err)
+			continue
+		}
+		list = append(list, normalize(reply))
+	}
+	return list, first
+}
@@ -0,0 +1,373 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pingInterval   = 30 * time.Second // 空闲时发送 PING 检查连接
+	reconnectDelay = time.Second
+)
+
+// Message 订阅收到的消息, 模式订阅时 Pattern 为匹配的模式
+type Message struct {
+	Channel string
+	Pattern string
+	Data    []byte
+}
+
+// PubSub 订阅, 连接断开后自动重连并重新订阅所有频道和模式, 重连期间的消息会丢失; 使用完需 Close
+type PubSub struct {
+	dial     func() (redis.Conn, error)
+	mtx      sync.Mutex
+	conn     *redis.PubSubConn
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	ch       chan *Message
+	done     chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+func (hy *Redigo) Publish(channel string, message interface{}) (int64, error) {
+	conn := hy.pool.Get()
+	defer conn.Close()
+	return redis.Int64(conn.Do("PUBLISH", channel, message))
+}
+
+// Subscribe 订阅频道, 返回时已收到订阅确认
+func (hy *Redigo) Subscribe(channels ...string) (*PubSub, error) {
+	return hy.newPubSub(channels, nil)
+}
+
+// PSubscribe 按模式订阅, 例如 news.*
+func (hy *Redigo) PSubscribe(patterns ...string) (*PubSub, error) {
+	return hy.newPubSub(nil, patterns)
+}
+
+// newPubSub 订阅使用独立连接, 不占用连接池
+func (hy *Redigo) newPubSub(channels, patterns []string) (*PubSub, error) {
+	if len(channels)+len(patterns) <= 0 {
+		return nil, errors.New("param is err")
+	}
+	p := &PubSub{
+		dial:     hy.pool.Dial,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		ch:       make(chan *Message, 100),
+		done:     make(chan struct{}),
+	}
+	for _, c := range channels {
+		p.channels[c] = struct{}{}
+	}
+	for _, c := range patterns {
+		p.patterns[c] = struct{}{}
+	}
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	// 等待所有订阅确认, 确保返回后发布的消息不会丢失; 先确认的频道在此期间收到的消息随后投递
+	var pending []*Message
+	for n := len(p.channels) + len(p.patterns); n > 0; {
+		switch v := conn.ReceiveWithTimeout(pingInterval).(type) {
+		case error:
+			_ = conn.Close()
+			return nil, v
+		case redis.Subscription:
+			n--
+		case redis.Message:
+			pending = append(pending, &Message{Channel: v.Channel, Pattern: v.Pattern, Data: v.Data})
+		}
+	}
+	p.wg.Add(1)
+	go p.run(conn, pending)
+	return p, nil
+}
+
+func members(m map[string]struct{}) []interface{} {
+	list := make([]interface{}, 0, len(m))
+	for k := range m {
+		list = append(list, k)
+	}
+	return list
+}
+
+// connect 建立连接并订阅当前所有频道和模式
+func (p *PubSub) connect() (*redis.PubSubConn, error) {
+	c, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	conn := &redis.PubSubConn{Conn: c}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	select {
+	case <-p.done:
+		_ = conn.Close()
+		return nil, errors.New("pubsub closed")
+	default:
+	}
+	if len(p.channels) > 0 {
+		err = conn.Subscribe(members(p.channels)...)
+	}
+	if err == nil && len(p.patterns) > 0 {
+		err = conn.PSubscribe(members(p.patterns)...)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	p.conn = conn
+	return conn, nil
+}
+
+func (p *PubSub) run(conn *redis.PubSubConn, pending []*Message) {
+	defer p.wg.Done()
+	defer close(p.ch)
+	for _, msg := range pending {
+		select {
+		case p.ch <- msg:
+		case <-p.done:
+			_ = conn.Close()
+			return
+		}
+	}
+	for {
+		err := p.receive(conn)
+		_ = conn.Close()
+		// 重连期间的订阅变更只修改集合, 重连后统一订阅
+		p.mtx.Lock()
+		if p.conn == conn {
+			p.conn = nil
+		}
+		p.mtx.Unlock()
+		for {
+			select {
+			case <-p.done:
+				return
+			default:
+			}
+			log.Printf("pubsub receive err(%+v), reconnecting", err)
+			select {
+			case <-p.done:
+				return
+			case <-time.After(reconnectDelay):
+			}
+			if conn, err = p.connect(); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// receive 读取消息直到连接出错
+func (p *PubSub) receive(conn *redis.PubSubConn) error {
+	for {
+		var msg *Message
+		switch v := conn.ReceiveWithTimeout(pingInterval).(type) {
+		case redis.Message:
+			msg = &Message{Channel: v.Channel, Pattern: v.Pattern, Data: v.Data}
+		case error:
+			if e, ok := v.(net.Error); ok && e.Timeout() {
+				// 空闲超时发送 PING, 连接已断开时下次读取会报错
+				p.mtx.Lock()
+				err := conn.Ping("")
+				p.mtx.Unlock()
+				if err == nil {
+					continue
+				}
+				return err
+			}
+			return v
+		}
+		if msg == nil {
+			continue
+		}
+		select {
+		case p.ch <- msg:
+		case <-p.done:
+			return errors.New("pubsub closed")
+		}
+	}
+}
+
+// Channel 收到的消息, Close 后关闭
+func (p *PubSub) Channel() <-chan *Message {
+	return p.ch
+}
+
+// Subscribe 追加订阅频道, 重连后同样会订阅
+func (p *PubSub) Subscribe(channels ...string) error {
+	return p.update(channels, p.channels, true, func(args ...interface{}) error {
+		return p.conn.Subscribe(args...)
+	})
+}
+
+func (p *PubSub) PSubscribe(patterns ...string) error {
+	return p.update(patterns, p.patterns, true, func(args ...interface{}) error {
+		return p.conn.PSubscribe(args...)
+	})
+}
+
+func (p *PubSub) Unsubscribe(channels ...string) error {
+	return p.update(channels, p.channels, false, func(args ...interface{}) error {
+		return p.conn.Unsubscribe(args...)
+	})
+}
+
+func (p *PubSub) PUnsubscribe(patterns ...string) error {
+	return p.update(patterns, p.patterns, false, func(args ...interface{}) error {
+		return p.conn.PUnsubscribe(args...)
+	})
+}
+
+// update 修改订阅集合并发送到当前连接; 正在重连或发送失败时返回 nil, 由重连按新的集合订阅
+func (p *PubSub) update(names []string, set map[string]struct{}, add bool, send func(args ...interface{}) error) error {
+	if len(names) <= 0 {
+		return nil
+	}
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	args := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		if add {
+			set[name] = struct{}{}
+		} else {
+			delete(set, name)
+		}
+		args = append(args, name)
+	}
+	select {
+	case <-p.done:
+		return errors.New("pubsub closed")
+	default:
+	}
+	if p.conn == nil {
+		return nil
+	}
+	if err := send(args...); err != nil {
+		// 连接已断开, receive 随后报错并重连
+		log.Printf("pubsub send err(%+v), resubscribe after reconnect", err)
+	}
+	return nil
+}
+
+// Close 关闭连接, Channel 随后关闭
+func (p *PubSub) Close() error {
+	var err error
+	p.once.Do(func() {
+		p.mtx.Lock()
+		close(p.done)
+		if p.conn != nil {
+			err = p.conn.Close()
+		}
+		p.mtx.Unlock()
+		p.wg.Wait()
+	})
+	return err
+}
+
+// KeyEvent 键空间通知, 需要服务端开启 notify-keyspace-events, 例如过期事件为 Ex
+type KeyEvent struct {
+	Db    int
+	Key   string
+	Event string // 事件名, 例如 expired、evicted、del、set
+}
+
+// ParseKeyEvent 解析 __keyspace@<db>__:<key> 和 __keyevent@<db>__:<event> 频道的消息
+func ParseKeyEvent(channel, payload string) (*KeyEvent, bool) {
+	var keyspace bool
+	var rest string
+	if strings.HasPrefix(channel, "__keyspace@") {
+		keyspace, rest = true, channel[len("__keyspace@"):]
+	} else if strings.HasPrefix(channel, "__keyevent@") {
+		rest = channel[len("__keyevent@"):]
+	} else {
+		return nil, false
+	}
+	idx := strings.Index(rest, "__:")
+	if idx <= 0 {
+		return nil, false
+	}
+	db, err := strconv.Atoi(rest[:idx])
+	if err != nil {
+		return nil, false
+	}
+	if keyspace {
+		return &KeyEvent{Db: db, Key: rest[idx+3:], Event: payload}, true
+	}
+	return &KeyEvent{Db: db, Key: payload, Event: rest[idx+3:]}, true
+}
+
+// KeyEventSub 键空间通知订阅, 使用完需 Close
+type KeyEventSub struct {
+	ps *PubSub
+	ch chan *KeyEvent
+}
+
+// Channel 收到的通知, Close 后关闭
+func (s *KeyEventSub) Channel() <-chan *KeyEvent {
+	return s.ch
+}
+
+func (s *KeyEventSub) Close() error {
+	return s.ps.Close()
+}
+
+func (s *KeyEventSub) forward() {
+	defer close(s.ch)
+	for msg := range s.ps.Channel() {
+		ev, ok := ParseKeyEvent(msg.Channel, string(msg.Data))
+		if !ok {
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		case <-s.ps.done:
+			return
+		}
+	}
+}
+
+// KeyEvents 订阅指定事件, events 为空时订阅所有事件
+func (hy *Redigo) KeyEvents(db int, events ...string) (*KeyEventSub, error) {
+	if len(events) <= 0 {
+		events = []string{"*"}
+	}
+	patterns := make([]string, 0, len(events))
+	for _, event := range events {
+		patterns = append(patterns, fmt.Sprintf("__keyevent@%d__:%s", db, event))
+	}
+	return hy.keyEventSub(patterns)
+}
+
+// KeySpace 订阅匹配 key 的所有事件, keys 支持通配符, 为空时订阅所有 key
+func (hy *Redigo) KeySpace(db int, keys ...string) (*KeyEventSub, error) {
+	if len(keys) <= 0 {
+		keys = []string{"*"}
+	}
+	patterns := make([]string, 0, len(keys))
+	for _, key := range keys {
+		patterns = append(patterns, fmt.Sprintf("__keyspace@%d__:%s", db, key))
+	}
+	return hy.keyEventSub(patterns)
+}
+
+func (hy *Redigo) keyEventSub(patterns []string) (*KeyEventSub, error) {
+	ps, err := hy.PSubscribe(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	s := &KeyEventSub{ps: ps, ch: make(chan *KeyEvent, 100)}
+	go s.forward()
+	return s, nil
+}
@@ -1,44 +1,76 @@
 package redis
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gomodule/redigo/redis"
+	"strconv"
 	"time"
 )
 
 type RedisConfig struct {
-	Addrs        string
-	Password     string
-	MaxIdleConns int //最初的连接数量
-	MaxOpenConns int //连接池最大连接数量,不确定可以用0(0表示自动定义，按需分配)
-	MaxLifeTime  int //连接关闭时间100秒(100秒不使用将关闭)
+	Addrs           string
+	Password        string
+	MaxIdleConns    int      //最初的连接数量
+	MaxOpenConns    int      //连接池最大连接数量,不确定可以用0(0表示自动定义，按需分配)
+	MaxLifeTime     int      //连接关闭时间100秒(100秒不使用将关闭)
+	Type            string   // node/sentinel, 默认node
+	MasterName      string   // sentinel 模式的主节点名
+	SentinelAddrs   []string // sentinel 模式的哨兵地址
+	ReadFromReplica bool     // sentinel 模式下读命令发往从节点, 从节点可能有复制延迟
 }
 
 type Redigo struct {
-	pool *redis.Pool
+	pool    *redis.Pool
+	replica *redis.Pool
 }
 
 func InitRedis(conf *RedisConfig) (*Redigo, error) {
+	if len(conf.Type) <= 0 || conf.Type == "node" {
+		return &Redigo{pool: newPool(conf, func() (redis.Conn, error) {
+			return dial(conf.Addrs, conf.Password)
+		}, "")}, nil
+	}
+	if conf.Type != "sentinel" {
+		return nil, errors.New("config type error")
+	}
+	if len(conf.MasterName) <= 0 || len(conf.SentinelAddrs) <= 0 {
+		return nil, errors.New("config sentinel error")
+	}
+	// 每次建连都向哨兵查询当前主节点, 故障转移后旧连接在借出时校验角色失败被丢弃
+	hy := &Redigo{pool: newPool(conf, sentinelMasterDial(conf), "master")}
+	if conf.ReadFromReplica {
+		hy.replica = newPool(conf, sentinelReplicaDial(conf), "slave")
+	}
+	return hy, nil
+}
 
-	pool := &redis.Pool{
-		Dial: func() (conn redis.Conn, err error) {
-			c, err := redis.Dial("tcp", conf.Addrs)
-			if err != nil {
-				return nil, err
-			}
-			if conf.Password != "" { // 有可能没有密码
-				if _, err := c.Do("AUTH", conf.Password); err != nil {
-					_ = c.Close()
-					return nil, err
-				}
-			}
-			return c, nil
-		},
+func dial(addr, password string) (redis.Conn, error) {
+	c, err := redis.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if password != "" { // 有可能没有密码
+		if _, err := c.Do("AUTH", password); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// newPool role 不为空时借出连接校验节点角色, 否则 PING
+func newPool(conf *RedisConfig, dialFn func() (redis.Conn, error), role string) *redis.Pool {
+	return &redis.Pool{
+		Dial:        dialFn,
 		MaxIdle:     conf.MaxIdleConns,                             //最初的连接数量
 		MaxActive:   conf.MaxOpenConns,                             //连接池最大连接数量,不确定可以用0(0表示自动定义，按需分配)
 		IdleTimeout: time.Duration(conf.MaxLifeTime) * time.Second, //连接关闭时间100秒(100秒不使用将关闭)
 		Wait:        true,                                          //超过最大连接，是报错，还是等待
 		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if len(role) > 0 {
+				return checkRole(c, role)
+			}
 			_, err := c.Do("PING")
 			if err != nil {
 				return fmt.Errorf("ping redis error: %s", err)
@@ -46,234 +78,463 @@ func InitRedis(conf *RedisConfig) (*Redigo, error) {
 			return nil
 		},
 	}
-	return &Redigo{pool: pool}, nil
 }
 
-func (hy *Redigo) Get(key string) (string, error) {
+// readConn 开启 ReadFromReplica 时从从节点取连接, 从节点不可用时回退到主节点
+func (hy *Redigo) readConn() redis.Conn {
+	if hy.replica != nil {
+		conn := hy.replica.Get()
+		if conn.Err() == nil {
+			return conn
+		}
+		_ = conn.Close()
+	}
+	return hy.pool.Get()
+}
+
+func (hy *Redigo) do(cmd string, args ...interface{}) (interface{}, error) {
 	conn := hy.pool.Get()
 	defer conn.Close()
-	return redis.String(conn.Do("GET", key))
+	return conn.Do(cmd, args...)
 }
 
-func (hy *Redigo) Set(key string, value string) error {
-	conn := hy.pool.Get()
+// read 只读命令, 开启 ReadFromReplica 时发往从节点
+func (hy *Redigo) read(cmd string, args ...interface{}) (interface{}, error) {
+	conn := hy.readConn()
 	defer conn.Close()
-	_, err := conn.Do("SET", key, value)
+	return conn.Do(cmd, args...)
+}
+
+func (hy *Redigo) Ping() bool {
+	value, err := redis.String(hy.do("PING"))
+	return err == nil && value == "PONG"
+}
+
+// Close 关闭连接池, 已创建的订阅不受影响
+func (hy *Redigo) Close() error {
+	if hy.replica != nil {
+		_ = hy.replica.Close()
+	}
+	return hy.pool.Close()
+}
+
+func (hy *Redigo) GetConn() redis.Conn {
+	return hy.pool.Get()
+}
+
+////////////////////////////////////////
+// key
+////////////////////////////////////////
+
+func (hy *Redigo) Exists(keys []string) (int64, error) {
+	return redis.Int64(hy.read("EXISTS", convertSlice(keys)...))
+}
+
+func (hy *Redigo) Del(keys []string) error {
+	if len(keys) <= 0 {
+		return nil
+	}
+	_, err := hy.do("DEL", convertSlice(keys)...)
 	return err
 }
 
-func (hy *Redigo) SetEx(key string, value string, seconds int) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	_, err := conn.Do("SETEX", key, seconds, value)
+func (hy *Redigo) Expire(key string, expiration int) (bool, error) {
+	return redis.Bool(hy.do("EXPIRE", key, expiration))
+}
+
+func (hy *Redigo) Persist(key string) (bool, error) {
+	return redis.Bool(hy.do("PERSIST", key))
+}
+
+func (hy *Redigo) RenameNX(key, newKey string) (bool, error) {
+	return redis.Bool(hy.do("RENAMENX", key, newKey))
+}
+
+////////////////////////////////////////
+// string
+////////////////////////////////////////
+
+func (hy *Redigo) Incr(key string) (int64, error) {
+	return redis.Int64(hy.do("INCR", key))
+}
+
+func (hy *Redigo) IncrBy(key string, value int64) (int64, error) {
+	return redis.Int64(hy.do("INCRBY", key, value))
+}
+
+func (hy *Redigo) Decr(key string) (int64, error) {
+	return redis.Int64(hy.do("DECR", key))
+}
+
+func (hy *Redigo) DecrBy(key string, value int64) (int64, error) {
+	return redis.Int64(hy.do("DECRBY", key, value))
+}
+
+func (hy *Redigo) Set(key string, value interface{}) error {
+	_, err := hy.do("SET", key, value)
 	return err
 }
 
-func (hy *Redigo) SetNx(key string, value string) (bool, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	if res, err := redis.Int(conn.Do("SETNX", key, value)); err != nil {
+// SetEx expiration 小于等于0时不过期
+func (hy *Redigo) SetEx(key string, value interface{}, expiration int) error {
+	args := redis.Args{}.Add(key, value)
+	if expiration > 0 {
+		args = args.Add("EX", expiration)
+	}
+	_, err := hy.do("SET", args...)
+	return err
+}
+
+// SetNx 返回是否设置成功, key 已存在时返回 false
+func (hy *Redigo) SetNx(key string, value interface{}) (bool, error) {
+	return redis.Bool(hy.do("SETNX", key, value))
+}
+
+// SetNxEx 返回是否设置成功, expiration 小于等于0时不过期
+func (hy *Redigo) SetNxEx(key string, value interface{}, expiration int) (bool, error) {
+	args := redis.Args{}.Add(key, value)
+	if expiration > 0 {
+		args = args.Add("EX", expiration)
+	}
+	rest, err := hy.do("SET", args.Add("NX")...)
+	if err != nil {
 		return false, err
-	} else {
-		return res >= 1, err
 	}
+	return rest != nil, nil
 }
 
-func (hy *Redigo) MGet(keys ...string) ([]string, error) {
+// Get key 不存在时返回 redis.ErrNil, 与 cache.Cache 的语义不同, 按接口使用时请通过 Cache()
+func (hy *Redigo) Get(key string) (string, error) {
+	return redis.String(hy.read("GET", key))
+}
 
-	conn := hy.pool.Get()
-	defer conn.Close()
-	var values []string
-	var err error
-	if values, err = redis.Strings(conn.Do("MGET", convertSlice(keys)...)); err != nil {
+func (hy *Redigo) MSet(keyValues map[string]interface{}) error {
+	_, err := hy.do("MSET", redis.Args{}.AddFlat(keyValues)...)
+	return err
+}
+
+func (hy *Redigo) MSetNx(keyValues map[string]interface{}) error {
+	_, err := hy.do("MSETNX", redis.Args{}.AddFlat(keyValues)...)
+	return err
+}
+
+// MGet key 不存在时对应的值为 nil
+func (hy *Redigo) MGet(keys []string) (map[string]interface{}, error) {
+	rest, err := redis.Values(hy.read("MGET", convertSlice(keys)...))
+	if err != nil {
 		return nil, err
 	}
-	if values != nil && len(values) > 0 {
-		for _, s := range values {
-			if s != "" {
-				return values, nil
-			}
+	values := make(map[string]interface{}, len(keys))
+	for i, key := range keys {
+		if i < len(rest) {
+			values[key] = normalize(rest[i])
 		}
 	}
-	return nil, nil
+	return values, nil
 }
 
-func (hy *Redigo) MSet(keyValues map[string]string) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	args := redis.Args{}.AddFlat(keyValues)
-	_, err := conn.Do("MSET", args...)
+////////////////////////////////////////
+// hash
+////////////////////////////////////////
+
+func (hy *Redigo) HSet(key, field string, value interface{}) error {
+	_, err := hy.do("HSET", key, field, value)
 	return err
 }
 
-func (hy *Redigo) Incr(key string) (int, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.Int(conn.Do("INCR", key))
+func (hy *Redigo) HSetNX(key, field string, value interface{}) error {
+	_, err := hy.do("HSETNX", key, field, value)
+	return err
 }
 
-func (hy *Redigo) IncrBy(key string, incr int) (int, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.Int(conn.Do("INCRBY", key, incr))
+func (hy *Redigo) HMSet(key string, fields map[string]interface{}) error {
+	_, err := hy.do("HMSET", redis.Args{}.Add(key).AddFlat(fields)...)
+	return err
 }
 
-func (hy *Redigo) HGet(key string, field string) (string, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.String(conn.Do("HGET", key, field))
+// HGet 字段不存在时返回 redis.ErrNil
+func (hy *Redigo) HGet(key, field string) (string, error) {
+	return redis.String(hy.read("HGET", key, field))
+}
+
+// HMGet 字段不存在时对应的值为 nil
+func (hy *Redigo) HMGet(key string, fields []string) ([]interface{}, error) {
+	rest, err := redis.Values(hy.read("HMGET", redis.Args{}.Add(key).AddFlat(fields)...))
+	if err != nil {
+		return nil, err
+	}
+	for i := range rest {
+		rest[i] = normalize(rest[i])
+	}
+	return rest, nil
 }
 
 func (hy *Redigo) HGetAll(key string) (map[string]string, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.StringMap(conn.Do("HGETALL", key))
+	return redis.StringMap(hy.read("HGETALL", key))
 }
 
-func (hy *Redigo) HSet(key string, field string, value string) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	_, err := conn.Do("HSET", key, field, value)
+func (hy *Redigo) HKeys(key string) ([]string, error) {
+	return redis.Strings(hy.read("HKEYS", key))
+}
+
+func (hy *Redigo) HVals(key string) ([]string, error) {
+	return redis.Strings(hy.read("HVALS", key))
+}
+
+func (hy *Redigo) HDel(key string, fields []string) error {
+	_, err := hy.do("HDEL", redis.Args{}.Add(key).AddFlat(fields)...)
 	return err
 }
 
-func (hy *Redigo) HMGet(key string, fields ...string) ([]string, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	args := redis.Args{}.Add(key).AddFlat(convertSlice(fields))
-	var values []string
-	var err error
-	if values, err = redis.Strings(conn.Do("HMGET", args...)); err != nil {
-		return nil, err
+func (hy *Redigo) HExists(key, field string) (bool, error) {
+	return redis.Bool(hy.read("HEXISTS", key, field))
+}
+
+func (hy *Redigo) HIncrBy(key, field string, incr int64) (int64, error) {
+	return redis.Int64(hy.do("HINCRBY", key, field, incr))
+}
+
+////////////////////////////////////////
+// set
+////////////////////////////////////////
+
+func (hy *Redigo) SAdd(key string, member interface{}) (int64, error) {
+	return redis.Int64(hy.do("SADD", key, member))
+}
+
+func (hy *Redigo) SAdds(key string, members []interface{}) (int64, error) {
+	return redis.Int64(hy.do("SADD", redis.Args{}.Add(key).Add(members...)...))
+}
+
+func (hy *Redigo) SCard(key string) (int64, error) {
+	return redis.Int64(hy.read("SCARD", key))
+}
+
+func (hy *Redigo) SMembers(key string) ([]string, error) {
+	return redis.Strings(hy.read("SMEMBERS", key))
+}
+
+func (hy *Redigo) SIsMember(key string, member interface{}) (bool, error) {
+	return redis.Bool(hy.read("SISMEMBER", key, member))
+}
+
+func (hy *Redigo) SRem(key string, member interface{}) (int64, error) {
+	return redis.Int64(hy.do("SREM", key, member))
+}
+
+func (hy *Redigo) SRems(key string, members []interface{}) (int64, error) {
+	return redis.Int64(hy.do("SREM", redis.Args{}.Add(key).Add(members...)...))
+}
+
+func (hy *Redigo) SInter(keys []string) ([]string, error) {
+	return redis.Strings(hy.read("SINTER", convertSlice(keys)...))
+}
+
+func (hy *Redigo) SDiff(keys []string) ([]string, error) {
+	return redis.Strings(hy.read("SDIFF", convertSlice(keys)...))
+}
+
+func (hy *Redigo) SUnion(keys []string) ([]string, error) {
+	return redis.Strings(hy.read("SUNION", convertSlice(keys)...))
+}
+
+////////////////////////////////////////
+// sorted set
+////////////////////////////////////////
+
+func (hy *Redigo) ZAdd(key string, value string, score int64) (int64, error) {
+	return redis.Int64(hy.do("ZADD", key, score, value))
+}
+
+func (hy *Redigo) ZAdds(key string, valueScore map[string]int64) (int64, error) {
+	args := redis.Args{}.Add(key)
+	for k, v := range valueScore {
+		args = args.Add(v, k)
 	}
-	if values != nil && len(values) > 0 {
-		for _, s := range values {
-			if s != "" {
-				return values, nil
-			}
-		}
+	return redis.Int64(hy.do("ZADD", args...))
+}
+
+func (hy *Redigo) ZAddNX(key string, value string, score int64) (int64, error) {
+	return redis.Int64(hy.do("ZADD", key, "NX", score, value))
+}
+
+func (hy *Redigo) ZAddsNX(key string, valueScore map[string]int64) (int64, error) {
+	args := redis.Args{}.Add(key, "NX")
+	for k, v := range valueScore {
+		args = args.Add(v, k)
 	}
-	return nil, nil
+	return redis.Int64(hy.do("ZADD", args...))
 }
 
-func (hy *Redigo) HMSet(key string, fieldValues map[string]string) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	args := redis.Args{}.Add(key).AddFlat(fieldValues)
-	_, err := conn.Do("HMSET", args...)
-	return err
+func (hy *Redigo) ZCard(key string) (int64, error) {
+	return redis.Int64(hy.read("ZCARD", key))
 }
 
-func (hy *Redigo) SAdd(key string, members ...string) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	args := redis.Args{}.Add(key).AddFlat(convertSlice(members))
-	_, err := conn.Do("SADD", args...)
-	return err
+func (hy *Redigo) ZCount(key string, min, max int64) (int64, error) {
+	return redis.Int64(hy.read("ZCOUNT", key, min, max))
 }
 
-func (hy *Redigo) SMembers(key string) ([]string, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.Strings(conn.Do("SMEMBERS", key))
+func (hy *Redigo) ZRange(key string, start, end int64) ([]string, error) {
+	return redis.Strings(hy.read("ZRANGE", key, start, end))
 }
 
-func (hy *Redigo) SRem(key string, members ...string) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	args := redis.Args{}.Add(key).AddFlat(convertSlice(members))
-	_, err := conn.Do("SREM", args...)
-	return err
+func (hy *Redigo) ZRangeWithScores(key string, start, end int64) (map[string]int64, error) {
+	return scoreMap(hy.read("ZRANGE", key, start, end, "WITHSCORES"))
 }
 
-func (hy *Redigo) SIsMember(key string, member string) (bool, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	do, err := redis.Int(conn.Do("SISMEMBER", key, member))
+// scoreRange min、max 为-1时表示无穷
+func scoreRange(min, max int64) (interface{}, interface{}) {
+	var lo, hi interface{} = min, max
+	if min == -1 {
+		lo = "-inf"
+	}
+	if max == -1 {
+		hi = "+inf"
+	}
+	return lo, hi
+}
+
+func (hy *Redigo) ZRangeByScore(key string, min, max int64) ([]string, error) {
+	lo, hi := scoreRange(min, max)
+	return redis.Strings(hy.read("ZRANGEBYSCORE", key, lo, hi))
+}
+
+func (hy *Redigo) ZRangeByScoreWithScores(key string, min, max int64) (map[string]int64, error) {
+	lo, hi := scoreRange(min, max)
+	return scoreMap(hy.read("ZRANGEBYSCORE", key, lo, hi, "WITHSCORES"))
+}
+
+func (hy *Redigo) ZRem(key string, fields ...interface{}) (int64, error) {
+	return redis.Int64(hy.do("ZREM", redis.Args{}.Add(key).Add(fields...)...))
+}
+
+func (hy *Redigo) ZRemRangeByScore(key string, min, max int64) (int64, error) {
+	return redis.Int64(hy.do("ZREMRANGEBYSCORE", key, min, max))
+}
+
+// ZScore 成员不存在时返回 redis.ErrNil
+func (hy *Redigo) ZScore(key string, value string) (int64, error) {
+	score, err := redis.Float64(hy.read("ZSCORE", key, value))
 	if err != nil {
-		return false, err
+		return 0, err
 	}
-	return do == 1, nil
+	return int64(score), nil
 }
 
-func (hy *Redigo) ZAdd(key string, values map[string]int) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	args := redis.Args{}.Add(key)
-	for k, v := range values {
-		args = args.Add(v).Add(k)
+func (hy *Redigo) ZIncrBy(key string, value string, incr int64) (int64, error) {
+	score, err := redis.Float64(hy.do("ZINCRBY", key, incr, value))
+	if err != nil {
+		return 0, err
 	}
-	_, err := conn.Do("ZADD", args...)
-	return err
+	return int64(score), nil
 }
 
-func (hy *Redigo) ZRange(key string, start, end int) ([]string, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.Strings(conn.Do("ZRANGE", key, start, end))
+// ZRank 按分数从小到大的排名, 从0开始, 成员不存在时返回 redis.ErrNil
+func (hy *Redigo) ZRank(key string, value string) (int64, error) {
+	return redis.Int64(hy.read("ZRANK", key, value))
 }
 
-func (hy *Redigo) ZRangeWithScores(key string, start, end int) (map[string]int, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.IntMap(conn.Do("ZRANGE", key, start, end, "withscores"))
+// ZRevRank 按分数从大到小的排名, 从0开始, 成员不存在时返回 redis.ErrNil
+func (hy *Redigo) ZRevRank(key string, value string) (int64, error) {
+	return redis.Int64(hy.read("ZREVRANK", key, value))
 }
 
-func (hy *Redigo) ZRangeByScore(key string, min, max interface{}) ([]string, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.Strings(conn.Do("ZRANGEBYSCORE", key, min, max))
+func (hy *Redigo) ZRevRange(key string, start, end int64) ([]string, error) {
+	return redis.Strings(hy.read("ZREVRANGE", key, start, end))
 }
 
-func (hy *Redigo) ZRangeByScoreWithScores(key string, min, max interface{}) (map[string]int, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.IntMap(conn.Do("ZRANGEBYSCORE", key, min, max, "withscores"))
+// scoreMap 解析 WITHSCORES 的回复, 分数取整
+func scoreMap(reply interface{}, err error) (map[string]int64, error) {
+	values, err := redis.Strings(reply, err)
+	if err != nil {
+		return nil, err
+	}
+	valueScore := make(map[string]int64, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		score, err := strconv.ParseFloat(values[i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+		valueScore[values[i]] = int64(score)
+	}
+	return valueScore, nil
 }
 
-func (hy *Redigo) ZRem(key string, members ...string) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	args := redis.Args{}.Add(key).AddFlat(convertSlice(members))
-	_, err := conn.Do("ZREM", args...)
-	return err
+////////////////////////////////////////
+// bitmap
+////////////////////////////////////////
+
+func (hy *Redigo) SetBit(key string, offset int64, value int) (int64, error) {
+	return redis.Int64(hy.do("SETBIT", key, offset, value))
 }
 
-func (hy *Redigo) ZScore(key string, member string) (int, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	return redis.Int(conn.Do("ZSCORE", key, member))
+func (hy *Redigo) GetBit(key string, offset int64) (int64, error) {
+	return redis.Int64(hy.read("GETBIT", key, offset))
 }
 
-func (hy *Redigo) Del(keys ...string) error {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	_, err := conn.Do("DEL", convertSlice(keys)...)
+func (hy *Redigo) BitCount(key string, start, end int64) (int64, error) {
+	return redis.Int64(hy.read("BITCOUNT", key, start, end))
+}
+
+////////////////////////////////////////
+// hyperloglog
+////////////////////////////////////////
+
+func (hy *Redigo) PFAdd(key string, els ...interface{}) (int64, error) {
+	return redis.Int64(hy.do("PFADD", redis.Args{}.Add(key).Add(els...)...))
+}
+
+func (hy *Redigo) PFCount(keys ...string) (int64, error) {
+	return redis.Int64(hy.read("PFCOUNT", convertSlice(keys)...))
+}
+
+func (hy *Redigo) PFMerge(dest string, keys ...string) error {
+	_, err := hy.do("PFMERGE", redis.Args{}.Add(dest).AddFlat(keys)...)
 	return err
 }
 
-func (hy *Redigo) Exists(key string) (bool, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	if res, err := redis.Int(conn.Do("EXISTS", key)); err != nil {
-		return false, err
-	} else {
-		return res == 1, err
+////////////////////////////////////////
+// 其他高级属性
+////////////////////////////////////////
+
+// normalize 回复中的 []byte 转为 string, 与 go-redis 一致
+func normalize(reply interface{}) interface{} {
+	switch v := reply.(type) {
+	case []byte:
+		return string(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
 	}
+	return reply
 }
 
-func (hy *Redigo) Expire(key string, seconds int) (bool, error) {
-	conn := hy.pool.Get()
-	defer conn.Close()
-	if res, err := redis.Int(conn.Do("EXPIRE", key, seconds)); err != nil {
-		return false, err
-	} else {
-		return res == 1, err
+// result 与 go-redis 一致, 回复为 nil 时返回 redis.ErrNil
+func result(reply interface{}, err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, redis.ErrNil
 	}
+	return normalize(reply), nil
 }
 
-func (hy *Redigo) GetConn() redis.Conn {
-	return hy.pool.Get()
+func (hy *Redigo) Eval(script string, keys []string, args []interface{}) (interface{}, error) {
+	return result(hy.do("EVAL", redis.Args{}.Add(script, len(keys)).AddFlat(keys).Add(args...)...))
+}
+
+func (hy *Redigo) EvalSha(script string, keys []string, args []interface{}) (interface{}, error) {
+	return result(hy.do("EVALSHA", redis.Args{}.Add(script, len(keys)).AddFlat(keys).Add(args...)...))
+}
+
+// Do 执行任意命令, 用于封装中没有的命令
+func (hy *Redigo) Do(args ...interface{}) (interface{}, error) {
+	if len(args) <= 0 {
+		return nil, errors.New("param is err")
+	}
+	return result(hy.do(fmt.Sprint(args[0]), args[1:]...))
 }
 
 func convertSlice(keys []string) []interface{} {
@@ -37,13 +37,13 @@ func Test_Get(t *testing.T) {
 		{"getExitKey", randomStr(), func(key string) error {
 			return cli.Set(key, "valid value")
 		}, func(key string) error {
-			return cli.Del(key)
+			return cli.Del([]string{key})
 		}, "valid value", false},
 		//
 		{"getExitKeyWithEmptyValue", randomStr(), func(key string) error {
 			return cli.Set(key, "")
 		}, func(key string) error {
-			return cli.Del(key)
+			return cli.Del([]string{key})
 		}, "", false},
 		//
 	}
@@ -83,20 +83,20 @@ func Test_MGet(t *testing.T) {
 		keys        []string
 		beforeStart func(keys []string) error
 		onEnd       func(keys []string) error
-		wants       []string
+		wants       []interface{}
 		wantErr     bool
 	}{
 		{"getNotExitKey", randomStrs(3), nil, nil, nil, false},
 		// key1 and key3 exist, key3 does not exist, expect get []{"valid key","","valid key}
 		{"getOnKeyAndOneInvalidKey", randomStrs(3), func(keys []string) error {
-			clis := make(map[string]string)
+			clis := make(map[string]interface{})
 			clis[keys[0]] = "valid key"
 			// clis[keys[1]] // not exist
 			clis[keys[2]] = "valid key"
 			return cli.MSet(clis)
 		}, func(keys []string) error {
-			return cli.Del(keys...)
-		}, []string{"valid key", "", "valid key"}, false},
+			return cli.Del(keys)
+		}, []interface{}{"valid key", nil, "valid key"}, false},
 		//
 	}
 	for _, tt := range tests {
@@ -106,7 +106,7 @@ func Test_MGet(t *testing.T) {
 					t.Errorf("MGet()  run beforeStart err %v", err)
 				}
 			}
-			gots, err := cli.MGet(tt.keys...)
+			gots, err := cli.MGet(tt.keys)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("MGet() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -115,8 +115,8 @@ func Test_MGet(t *testing.T) {
 					t.Errorf("MGet get len %d, want len %d", len(gots), len(tt.wants))
 				} else {
 					for i, want := range tt.wants {
-						if want != gots[i] {
-							t.Errorf("MGet get %v, want %v", gots[i], want)
+						if want != gots[tt.keys[i]] {
+							t.Errorf("MGet get %v, want %v", gots[tt.keys[i]], want)
 						}
 					}
 				}
@@ -167,7 +167,7 @@ func Test_Del(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := cli.Del(tt.args...); (err != nil) != tt.wantErr {
+			if err := cli.Del(tt.args); (err != nil) != tt.wantErr {
 				t.Errorf("Del() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -182,7 +182,7 @@ func Test_Exists(t *testing.T) {
 	exitKey := randomStr()
 	notExitKey := randomStr()
 
-	defer cli.Del(exitKey, notExitKey)
+	defer cli.Del([]string{exitKey, notExitKey})
 
 	// 设置测试值
 	if err = cli.SetEx(exitKey, "valid key", 10); err != nil {
@@ -191,12 +191,13 @@ func Test_Exists(t *testing.T) {
 
 	tests := []struct {
 		name    string
-		args    string
-		want    bool
+		args    []string
+		want    int64
 		wantErr bool
 	}{
-		{"exitKey", exitKey, true, false},
-		{"notExitKey", notExitKey, false, false},
+		{"exitKey", []string{exitKey}, 1, false},
+		{"notExitKey", []string{notExitKey}, 0, false},
+		{"exitAndNotExitKey", []string{exitKey, notExitKey}, 1, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -225,7 +226,7 @@ func Test_Expire(t *testing.T) {
 	// prepare test data
 	exitKey := randomStr()
 	exitValue := "exit value"
-	defer cli.Del(exitKey)
+	defer cli.Del([]string{exitKey})
 	if err = cli.Set(exitKey, exitValue); err != nil {
 		t.Fatal("expect success")
 	}
@@ -249,6 +250,10 @@ func Test_Expire(t *testing.T) {
 	if _, err := cli.Get(exitKey); err == nil {
 		t.Fatal("expect get err")
 	}
+	// Cache 接口语义: 不存在时返回空字符串
+	if v, err := cli.Cache().Get(exitKey); err != nil || v != "" {
+		t.Fatalf("expect get empty value, but get %v %v", v, err)
+	}
 }
 
 func Test_HGet(t *testing.T) {
@@ -265,7 +270,7 @@ func Test_HGet(t *testing.T) {
 	if err := cli.HSet(key, field, value); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	type args struct {
 		key   string
@@ -309,7 +314,7 @@ func Test_HMGet(t *testing.T) {
 	if err := cli.HSet(key, field, value); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	type args struct {
 		key   string
@@ -318,18 +323,18 @@ func Test_HMGet(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    []string
+		want    []interface{}
 		wantErr bool
 	}{
-		{"exitData", args{key, []string{"name"}}, []string{"bob"}, false},
-		{"exitAndNoExist", args{key, []string{"name", "age"}}, []string{"bob", ""}, false},
-		{"notExitField", args{key, []string{"wrong"}}, nil, false},
-		{"notExitKey", args{"invalidKey", []string{"wrong"}}, nil, false},
+		{"exitData", args{key, []string{"name"}}, []interface{}{"bob"}, false},
+		{"exitAndNoExist", args{key, []string{"name", "age"}}, []interface{}{"bob", nil}, false},
+		{"notExitField", args{key, []string{"wrong"}}, []interface{}{nil}, false},
+		{"notExitKey", args{"invalidKey", []string{"wrong"}}, []interface{}{nil}, false},
 	}
 	for i, tt := range tests {
 		_ = i
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := cli.HMGet(tt.args.key, tt.args.field...)
+			got, err := cli.HMGet(tt.args.key, tt.args.field)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("HGet() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -350,17 +355,17 @@ func Test_HMSet(t *testing.T) {
 	}
 	// prepare test data
 	key := randomStr()
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	field := "name"
 	value := "bob"
 
-	fieldValue := make(map[string]string)
+	fieldValue := make(map[string]interface{})
 	fieldValue[field] = value
 	if err := cli.HMSet(key, fieldValue); err != nil {
 		t.Fatal(err)
 	}
 
-	if get, err := cli.HMGet(key, field); err != nil {
+	if get, err := cli.HMGet(key, []string{field}); err != nil {
 		t.Fatal(err)
 	} else {
 		if get == nil {
@@ -379,11 +384,11 @@ func Test_Incr(t *testing.T) {
 	}
 	// prepare test data
 	key := randomStr()
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	tests := []struct {
 		name    string
 		key     string
-		want    int
+		want    int64
 		wantErr bool
 	}{
 		{"baseOnNil", key, 1, false},
@@ -411,15 +416,15 @@ func Test_IncrBy(t *testing.T) {
 	}
 	// prepare test data
 	key := randomStr()
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	type args struct {
 		key  string
-		incr int
+		incr int64
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    int
+		want    int64
 		wantErr bool
 	}{
 		{"plus", args{key, 10}, 10, false},
@@ -451,13 +456,13 @@ func Test_MSet(t *testing.T) {
 	existKey2 := randomStr()
 	existValue2 := randomStr()
 
-	clis := make(map[string]string)
+	clis := make(map[string]interface{})
 	clis[existKey1] = existValue1
 	clis[existKey2] = existValue2
 	if err = cli.MSet(clis); err != nil {
 		t.Fatal(err)
 	}
-	cli.Del(existKey1, existKey2)
+	cli.Del([]string{existKey1, existKey2})
 }
 
 func Test_SAdd(t *testing.T) {
@@ -467,23 +472,29 @@ func Test_SAdd(t *testing.T) {
 	}
 	// prepare test data
 	key := randomStr()
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	type args struct {
 		key     string
-		members []string
+		members []interface{}
 	}
 	tests := []struct {
 		name    string
 		args    args
+		want    int64
 		wantErr bool
 	}{
-		{"sadd", args{key, []string{"v1", "v2", "v3"}}, false},
-		{"duplicate", args{key, []string{"v0", "v2", "v3", "v4"}}, false},
+		{"sadd", args{key, []interface{}{"v1", "v2", "v3"}}, 3, false},
+		{"duplicate", args{key, []interface{}{"v0", "v2", "v3", "v4"}}, 2, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := cli.SAdd(tt.args.key, tt.args.members...); (err != nil) != tt.wantErr {
-				t.Errorf("SAdd() error = %v, wantErr %v", err, tt.wantErr)
+			got, err := cli.SAdds(tt.args.key, tt.args.members)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SAdds() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("SAdds() got = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -499,11 +510,11 @@ func Test_SIsMember(t *testing.T) {
 	existValue := randomStr()
 	noExistValue := randomStr()
 
-	if err := cli.SAdd(key, existValue); err != nil {
+	if _, err := cli.SAdd(key, existValue); err != nil {
 		t.Fatal(err)
 	}
 
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	type args struct {
 		key    string
@@ -543,10 +554,10 @@ func Test_SMembers(t *testing.T) {
 	member1 := randomStr()
 	member2 := randomStr()
 
-	if err := cli.SAdd(key, member1, member2); err != nil {
+	if _, err := cli.SAdds(key, []interface{}{member1, member2}); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	tests := []struct {
 		name    string
@@ -578,26 +589,26 @@ func Test_SRem(t *testing.T) {
 	key := randomStr()
 	m1 := randomStr()
 	m2 := randomStr()
-	if err := cli.SAdd(key, m1, m2); err != nil {
+	if _, err := cli.SAdds(key, []interface{}{m1, m2}); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	// prepare test data
 	type args struct {
 		key     string
-		members []string
+		members []interface{}
 	}
 	tests := []struct {
 		name    string
 		args    args
 		wantErr bool
 	}{
-		{"existMember", args{key, []string{m1, m2}}, false},
-		{"notExistMember", args{key, []string{randomStr()}}, false},
+		{"existMember", args{key, []interface{}{m1, m2}}, false},
+		{"notExistMember", args{key, []interface{}{randomStr()}}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := cli.SRem(tt.args.key, tt.args.members...); (err != nil) != tt.wantErr {
+			if _, err := cli.SRems(tt.args.key, tt.args.members); (err != nil) != tt.wantErr {
 				t.Errorf("SRem() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -614,7 +625,7 @@ func Test_SetEx(t *testing.T) {
 		t.Fatal(err)
 	}
 	time.Sleep(time.Second)
-	if _, err := cli.Get(key); err == nil {
+	if v, err := cli.Get(key); err != nil || v != "" {
 		t.Fatal("expect expired")
 	}
 }
@@ -629,7 +640,7 @@ func Test_SetNx(t *testing.T) {
 	if err := cli.Set(existKey, randomStr()); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(existKey, notExistKey)
+	defer cli.Del([]string{existKey, notExistKey})
 	type args struct {
 		key   string
 		value string
@@ -665,15 +676,15 @@ func Test_ZAdd(t *testing.T) {
 	key := randomStr()
 	v1 := randomStr()
 	v2 := randomStr()
-	vs := make(map[string]int)
+	vs := make(map[string]int64)
 	vs[v1] = 1
 	vs[v2] = 2
 
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	type args struct {
 		key    string
-		values map[string]int
+		values map[string]int64
 	}
 	tests := []struct {
 		name    string
@@ -684,8 +695,8 @@ func Test_ZAdd(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := cli.ZAdd(tt.args.key, tt.args.values); (err != nil) != tt.wantErr {
-				t.Errorf("ZAdd() error = %v, wantErr %v", err, tt.wantErr)
+			if _, err := cli.ZAdds(tt.args.key, tt.args.values); (err != nil) != tt.wantErr {
+				t.Errorf("ZAdds() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
@@ -699,37 +710,37 @@ func Test_ZRem(t *testing.T) {
 	key := randomStr()
 	v1 := randomStr()
 	v2 := randomStr()
-	vs := make(map[string]int)
+	vs := make(map[string]int64)
 	vs[v1] = 1
 	vs[v2] = 2
-	if err := cli.ZAdd(key, vs); err != nil {
+	if _, err := cli.ZAdds(key, vs); err != nil {
 		t.Fatal(err)
 	}
 
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	type args struct {
 		key     string
-		members []string
+		members []interface{}
 	}
 	tests := []struct {
 		name    string
 		args    args
 		wantErr bool
 	}{
-		{"valid", args{key, []string{v1}}, false},
-		{"validWithInvalid", args{key, []string{v1, randomStr()}}, false},
+		{"valid", args{key, []interface{}{v1}}, false},
+		{"validWithInvalid", args{key, []interface{}{v1, randomStr()}}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := cli.ZRem(tt.args.key, tt.args.members...); (err != nil) != tt.wantErr {
+			if _, err := cli.ZRem(tt.args.key, tt.args.members...); (err != nil) != tt.wantErr {
 				t.Errorf("ZRem() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func TestcliRedis_ZScore(t *testing.T) {
+func Test_ZScore(t *testing.T) {
 	cli, err := InitRedis(&rConf)
 	if err != nil {
 		t.Fatal(err)
@@ -737,14 +748,14 @@ func TestcliRedis_ZScore(t *testing.T) {
 	key := randomStr()
 	v1 := randomStr()
 	v2 := randomStr()
-	vs := make(map[string]int)
+	vs := make(map[string]int64)
 	vs[v1] = 1
 	vs[v2] = 2
-	if err := cli.ZAdd(key, vs); err != nil {
+	if _, err := cli.ZAdds(key, vs); err != nil {
 		t.Fatal(err)
 	}
 
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	type args struct {
 		key    string
 		member string
@@ -752,7 +763,7 @@ func TestcliRedis_ZScore(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    int
+		want    int64
 		wantErr bool
 	}{
 		{"v1", args{key, v1}, 1, false},
@@ -783,19 +794,19 @@ func Test_ZRange(t *testing.T) {
 	v1 := randomStr()
 	v2 := randomStr()
 
-	clis := make(map[string]int)
+	clis := make(map[string]int64)
 	clis[v0] = 1
 	clis[v1] = 2
 	clis[v2] = 3
-	if err := cli.ZAdd(key, clis); err != nil {
+	if _, err := cli.ZAdds(key, clis); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	type args struct {
 		key   string
-		start int
-		end   int
+		start int64
+		end   int64
 	}
 	tests := []struct {
 		name    string
@@ -831,23 +842,23 @@ func Test_ZRangeWithScores(t *testing.T) {
 	v1 := randomStr()
 	v2 := randomStr()
 
-	clis := make(map[string]int)
+	clis := make(map[string]int64)
 	clis[v0] = 1
 	clis[v1] = 2
 	clis[v2] = 3
-	if err := cli.ZAdd(key, clis); err != nil {
+	if _, err := cli.ZAdds(key, clis); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	type args struct {
 		key   string
-		start int
-		end   int
+		start int64
+		end   int64
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    map[string]int
+		want    map[string]int64
 		wantErr bool
 	}{
 		{"valid", args{key, 0, -1}, clis, false},
@@ -876,19 +887,19 @@ func Test_ZRangeByScore(t *testing.T) {
 	v1 := randomStr()
 	v2 := randomStr()
 
-	clis := make(map[string]int)
+	clis := make(map[string]int64)
 	clis[v0] = 1
 	clis[v1] = 2
 	clis[v2] = 3
-	if err := cli.ZAdd(key, clis); err != nil {
+	if _, err := cli.ZAdds(key, clis); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 
 	type args struct {
 		key   string
-		start interface{}
-		end   interface{}
+		start int64
+		end   int64
 	}
 	tests := []struct {
 		name    string
@@ -896,7 +907,7 @@ func Test_ZRangeByScore(t *testing.T) {
 		want    []string
 		wantErr bool
 	}{
-		{"getAll", args{key, "-inf", "+inf"}, []string{v0, v1, v2}, false},
+		{"getAll", args{key, -1, -1}, []string{v0, v1, v2}, false},
 		{"get0-1", args{key, 0, 1}, []string{v0}, false},
 		{"get2-1000", args{key, 2, 1000}, []string{v1, v2}, false},
 		{"nokey", args{"test", 0, 3}, []string{}, false},
@@ -927,29 +938,29 @@ func Test_ZRangeByScoreWithScores(t *testing.T) {
 	v1 := randomStr()
 	v2 := randomStr()
 
-	clis := make(map[string]int)
+	clis := make(map[string]int64)
 	clis[v0] = 1
 	clis[v1] = 2
 	clis[v2] = 3
-	if err := cli.ZAdd(key, clis); err != nil {
+	if _, err := cli.ZAdds(key, clis); err != nil {
 		t.Fatal(err)
 	}
-	defer cli.Del(key)
+	defer cli.Del([]string{key})
 	type args struct {
 		key   string
-		start interface{}
-		end   interface{}
+		start int64
+		end   int64
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    map[string]int
+		want    map[string]int64
 		wantErr bool
 	}{
-		{"getAll", args{key, "-inf", "+inf"}, clis, false},
+		{"getAll", args{key, -1, -1}, clis, false},
 		{"valid", args{key, 0, 4}, clis, false},
-		{"invalid", args{key, 4, 5}, map[string]int{}, false},
-		{"nokey", args{"test", 4, 5}, map[string]int{}, false},
+		{"invalid", args{key, 4, 5}, map[string]int64{}, false},
+		{"nokey", args{"test", 4, 5}, map[string]int64{}, false},
 	}
 	for i, tt := range tests {
 		_ = i
@@ -965,3 +976,85 @@ func Test_ZRangeByScoreWithScores(t *testing.T) {
 		})
 	}
 }
+
+func Test_Scan(t *testing.T) {
+	cli, err := InitRedis(&rConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := randomStr()
+	keys := make(map[string]interface{})
+	for i := 0; i < 250; i++ {
+		keys[prefix+":"+randomStr()] = i
+	}
+	if err = cli.MSet(keys); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	it := cli.Scan(prefix+":*", 50)
+	for it.Next() {
+		got[it.Val()] = true
+	}
+	if err = it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(keys) {
+		t.Errorf("Scan() got %d keys, want %d", len(got), len(keys))
+	}
+	n, err := cli.DelPattern(prefix + ":*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(keys)) {
+		t.Errorf("DelPattern() got = %v, want %v", n, len(keys))
+	}
+}
+
+func Test_HScan(t *testing.T) {
+	cli, err := InitRedis(&rConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := randomStr()
+	defer cli.Del([]string{key})
+	fields := map[string]interface{}{"name": "bob", "age": "18"}
+	if err = cli.HMSet(key, fields); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]interface{})
+	it := cli.HScan(key, "", 0)
+	for it.Next() {
+		field := it.Val()
+		if !it.Next() {
+			t.Fatal("expect value after field")
+		}
+		got[field] = it.Val()
+	}
+	if err = it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, fields) {
+		t.Errorf("HScan() got = %v, want %v", got, fields)
+	}
+}
+
+func Test_Pipelined(t *testing.T) {
+	cli, err := InitRedis(&rConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := randomStr()
+	defer cli.Del([]string{key})
+	got, err := cli.Pipelined(func(p *Pipeline) error {
+		_ = p.Send("SET", key, "1")
+		_ = p.Send("INCR", key)
+		return p.Send("GET", key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"OK", int64(2), "2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pipelined() got = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/y1015860449/gotoolkit/cache"
+)
+
+var _ cache.Cache = (*redigoCache)(nil)
+
+// scanIterator SCAN 系列命令的游标迭代器, 每次取一页
+type scanIterator struct {
+	hy      *Redigo
+	cmd     string
+	key     string
+	match   string
+	count   int64
+	cursor  int64
+	started bool
+	page    []string
+	pos     int
+	err     error
+}
+
+func (it *scanIterator) Next() bool {
+	for it.pos >= len(it.page) {
+		if it.err != nil || (it.started && it.cursor == 0) {
+			return false
+		}
+		if it.err = it.fetch(); it.err != nil {
+			return false
+		}
+	}
+	it.pos++
+	return true
+}
+
+func (it *scanIterator) Val() string {
+	if it.pos <= 0 || it.pos > len(it.page) {
+		return ""
+	}
+	return it.page[it.pos-1]
+}
+
+func (it *scanIterator) Err() error {
+	return it.err
+}
+
+func (it *scanIterator) fetch() error {
+	args := redis.Args{}
+	if len(it.key) > 0 {
+		args = args.Add(it.key)
+	}
+	args = args.Add(it.cursor)
+	if len(it.match) > 0 {
+		args = args.Add("MATCH", it.match)
+	}
+	if it.count > 0 {
+		args = args.Add("COUNT", it.count)
+	}
+	// 游标只在发出它的节点有效, 从节点连接每次可能连到不同节点, 因此固定发往主节点
+	rest, err := redis.Values(it.hy.do(it.cmd, args...))
+	if err != nil {
+		return err
+	}
+	var page []string
+	if _, err = redis.Scan(rest, &it.cursor, &page); err != nil {
+		return err
+	}
+	it.started, it.page, it.pos = true, page, 0
+	return nil
+}
+
+// Scan 遍历匹配的 key, count 为每次扫描的数量提示
+func (hy *Redigo) Scan(match string, count int64) cache.Iterator {
+	return &scanIterator{hy: hy, cmd: "SCAN", match: match, count: count}
+}
+
+func (hy *Redigo) HScan(key, match string, count int64) cache.Iterator {
+	return &scanIterator{hy: hy, cmd: "HSCAN", key: key, match: match, count: count}
+}
+
+func (hy *Redigo) SScan(key, match string, count int64) cache.Iterator {
+	return &scanIterator{hy: hy, cmd: "SSCAN", key: key, match: match, count: count}
+}
+
+func (hy *Redigo) ZScan(key, match string, count int64) cache.Iterator {
+	return &scanIterator{hy: hy, cmd: "ZSCAN", key: key, match: match, count: count}
+}
+
+// DelPattern 按批删除; 中途出错时已删除的不会恢复
+func (hy *Redigo) DelPattern(pattern string) (int64, error) {
+	var total int64
+	keys := make([]string, 0, 100)
+	flush := func() error {
+		n, err := redis.Int64(hy.do("DEL", convertSlice(keys)...))
+		total += n
+		keys = keys[:0]
+		return err
+	}
+	it := hy.Scan(pattern, 100)
+	for it.Next() {
+		if keys = append(keys, it.Val()); len(keys) >= 100 {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return total, err
+	}
+	if len(keys) > 0 {
+		if err := flush(); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
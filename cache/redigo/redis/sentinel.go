@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+var errNoReplica = errors.New("sentinel: no available replica")
+
+// queryMaster 依次询问哨兵, 返回第一个应答的哨兵记录的主节点地址
+func queryMaster(sentinels []string, masterName string) (string, error) {
+	var lastErr error
+	for _, addr := range sentinels {
+		vals, err := querySentinel(addr, "get-master-addr-by-name", masterName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		hostPort, err := redis.Strings(vals, nil)
+		if err != nil || len(hostPort) != 2 {
+			lastErr = fmt.Errorf("sentinel(%s) unknown master %s", addr, masterName)
+			continue
+		}
+		return net.JoinHostPort(hostPort[0], hostPort[1]), nil
+	}
+	return "", lastErr
+}
+
+// queryReplicas 返回健康的从节点, 忽略下线和断开的节点
+func queryReplicas(sentinels []string, masterName string) ([]string, error) {
+	var lastErr error
+	for _, addr := range sentinels {
+		vals, err := querySentinel(addr, "slaves", masterName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var addrs []string
+		for _, val := range vals {
+			info, err := redis.StringMap(val, nil)
+			if err != nil {
+				continue
+			}
+			if strings.Contains(info["flags"], "s_down") || strings.Contains(info["flags"], "o_down") ||
+				strings.Contains(info["flags"], "disconnected") || info["master-link-status"] == "err" {
+				continue
+			}
+			addrs = append(addrs, net.JoinHostPort(info["ip"], info["port"]))
+		}
+		return addrs, nil
+	}
+	return nil, lastErr
+}
+
+func querySentinel(addr string, args ...interface{}) ([]interface{}, error) {
+	c, err := redis.Dial("tcp", addr, redis.DialConnectTimeout(3*time.Second), redis.DialReadTimeout(3*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return redis.Values(c.Do("SENTINEL", args...))
+}
+
+// checkRole 故障转移后旧连接可能指向降级的节点, 借出连接时校验角色
+func checkRole(c redis.Conn, want string) error {
+	vals, err := redis.Values(c.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(vals) <= 0 {
+		return errors.New("role reply is empty")
+	}
+	role, _ := redis.String(vals[0], nil)
+	if role != want {
+		return fmt.Errorf("redis role is %s, want %s", role, want)
+	}
+	return nil
+}
+
+func sentinelMasterDial(conf *RedisConfig) func() (redis.Conn, error) {
+	return func() (redis.Conn, error) {
+		addr, err := queryMaster(conf.SentinelAddrs, conf.MasterName)
+		if err != nil {
+			return nil, err
+		}
+		return dial(addr, conf.Password)
+	}
+}
+
+func sentinelReplicaDial(conf *RedisConfig) func() (redis.Conn, error) {
+	return func() (redis.Conn, error) {
+		addrs, err := queryReplicas(conf.SentinelAddrs, conf.MasterName)
+		if err != nil {
+			return nil, err
+		}
+		if len(addrs) <= 0 {
+			return nil, errNoReplica
+		}
+		return dial(addrs[rand.Intn(len(addrs))], conf.Password)
+	}
+}
@@ -0,0 +1,276 @@
+// Package config 分层配置加载, 支持 nacos/etcd/consul/zookeeper/本地文件, 变化时热更新并通知订阅者
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	v2 "gopkg.in/yaml.v2"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Source 配置来源, 内容为 yaml 或 json
+type Source interface {
+	// Read 读取当前内容, 不存在时返回 nil, nil
+	Read() ([]byte, error)
+	// Watch 内容变化时在其他 goroutine 调用 onChange(删除时为 nil), 非阻塞, ctx 取消时停止
+	Watch(ctx context.Context, onChange func(data []byte)) error
+}
+
+// Validator 配置结构实现该接口时, 每次加载后校验, 失败则保留旧配置
+type Validator interface {
+	Validate() error
+}
+
+// Decoder 将合并后的配置解析到结构体
+type Decoder func(m map[string]interface{}, v interface{}) error
+
+// YamlDecoder 默认解析方式, 按 yaml tag 匹配
+func YamlDecoder(m map[string]interface{}, v interface{}) error {
+	data, err := v2.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return v2.Unmarshal(data, v)
+}
+
+// JsonDecoder 按 json tag 匹配
+func JsonDecoder(m map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Config 多个 Source 按顺序合并, 后面的覆盖前面的
+type Config[T any] struct {
+	sources  []Source
+	defaults func() *T
+	validate func(*T) error
+	decoder  Decoder
+
+	mtx    sync.RWMutex
+	layers [][]byte
+	value  *T
+	subMtx sync.Mutex
+	subId  int
+	subs   map[int]func(old, new *T)
+	cancel context.CancelFunc
+	updMtx sync.Mutex // 保证重新加载和通知的顺序
+}
+
+// New defaults 为空时从零值开始, 通常传入 DefaultXxxConfig
+func New[T any](defaults func() *T, sources ...Source) *Config[T] {
+	return &Config[T]{
+		sources:  sources,
+		defaults: defaults,
+		decoder:  YamlDecoder,
+		layers:   make([][]byte, len(sources)),
+		subs:     make(map[int]func(old, new *T)),
+	}
+}
+
+// WithValidate 额外的校验函数
+func (c *Config[T]) WithValidate(fn func(*T) error) *Config[T] {
+	c.validate = fn
+	return c
+}
+
+func (c *Config[T]) WithDecoder(d Decoder) *Config[T] {
+	if d != nil {
+		c.decoder = d
+	}
+	return c
+}
+
+// Load 读取所有 Source 并开始监听变化
+func (c *Config[T]) Load() (*T, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.cancel != nil {
+		return c.value, nil
+	}
+	for i, src := range c.sources {
+		data, err := src.Read()
+		if err != nil {
+			return nil, fmt.Errorf("config source(%d) read err(%+v)", i, err)
+		}
+		c.layers[i] = data
+	}
+	value, err := c.build(c.layers)
+	if err != nil {
+		return nil, err
+	}
+	c.value = value
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	for i, src := range c.sources {
+		idx := i
+		if err = src.Watch(ctx, func(data []byte) { c.update(idx, data) }); err != nil {
+			cancel()
+			c.cancel = nil
+			return nil, fmt.Errorf("config source(%d) watch err(%+v)", i, err)
+		}
+	}
+	return value, nil
+}
+
+// Get 当前配置, 返回值不可修改
+func (c *Config[T]) Get() *T {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.value
+}
+
+// Subscribe 配置变化后按订阅顺序同步回调, 返回取消函数
+func (c *Config[T]) Subscribe(fn func(old, new *T)) func() {
+	c.subMtx.Lock()
+	defer c.subMtx.Unlock()
+	c.subId++
+	id := c.subId
+	c.subs[id] = fn
+	return func() {
+		c.subMtx.Lock()
+		defer c.subMtx.Unlock()
+		delete(c.subs, id)
+	}
+}
+
+// Close 停止监听
+func (c *Config[T]) Close() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+func (c *Config[T]) update(idx int, data []byte) {
+	c.updMtx.Lock()
+	defer c.updMtx.Unlock()
+	c.mtx.Lock()
+	layers := make([][]byte, len(c.layers))
+	copy(layers, c.layers)
+	layers[idx] = data
+	value, err := c.build(layers)
+	if err != nil {
+		c.mtx.Unlock()
+		log.Printf("config source(%d) reload err(%+v)", idx, err)
+		return
+	}
+	old := c.value
+	c.layers, c.value = layers, value
+	c.mtx.Unlock()
+
+	c.subMtx.Lock()
+	ids := make([]int, 0, len(c.subs))
+	for id := range c.subs {
+		ids = append(ids, id)
+	}
+	subs := make([]func(old, new *T), 0, len(ids))
+	sort.Ints(ids)
+	for _, id := range ids {
+		subs = append(subs, c.subs[id])
+	}
+	c.subMtx.Unlock()
+	for _, fn := range subs {
+		fn(old, value)
+	}
+}
+
+func (c *Config[T]) build(layers [][]byte) (*T, error) {
+	merged := make(map[string]interface{})
+	for i, data := range layers {
+		if len(data) <= 0 {
+			continue
+		}
+		m, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("config source(%d) parse err(%+v)", i, err)
+		}
+		Merge(merged, m)
+	}
+	value := new(T)
+	if c.defaults != nil {
+		if def := c.defaults(); def != nil {
+			value = def
+		}
+	}
+	if err := c.decoder(merged, value); err != nil {
+		return nil, err
+	}
+	if v, ok := interface{}(value).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if c.validate != nil {
+		if err := c.validate(value); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// Parse 解析 yaml 或 json 内容
+func Parse(data []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		// json 缩进中的 tab 不是合法的 yaml
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	if err := v2.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return normalize(m).(map[string]interface{}), nil
+}
+
+// Merge 将 src 深度合并到 dst, map 递归合并, 其他类型直接覆盖
+func Merge(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		sm, ok := sv.(map[string]interface{})
+		if !ok {
+			dst[k] = sv
+			continue
+		}
+		dm, ok := dst[k].(map[string]interface{})
+		if !ok {
+			dm = make(map[string]interface{})
+			dst[k] = dm
+		}
+		Merge(dm, sm)
+	}
+}
+
+// normalize yaml.v2 的嵌套 map 为 map[interface{}]interface{}, 转为 string key 便于合并和 json 编码
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = normalize(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range t {
+			t[k] = normalize(val)
+		}
+		return t
+	case []interface{}:
+		for i, val := range t {
+			t[i] = normalize(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
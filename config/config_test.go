@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testRedis struct {
+	Addr       []string `yaml:"addr"`
+	Pwd        string   `yaml:"pwd"`
+	MaxRetries int      `yaml:"maxRetries"`
+}
+
+type testConfig struct {
+	ListenOn string     `yaml:"listenOn"`
+	Redis    *testRedis `yaml:"redis"`
+}
+
+func (c *testConfig) Validate() error {
+	if len(c.ListenOn) <= 0 {
+		return errors.New("listenOn is empty")
+	}
+	return nil
+}
+
+func defaultTestConfig() *testConfig {
+	return &testConfig{ListenOn: ":8080", Redis: &testRedis{MaxRetries: 3}}
+}
+
+func TestConfig(t *testing.T) {
+	base := Memory([]byte("redis:\n  addr: [\"127.0.0.1:6379\"]\n  pwd: base\n"))
+	override := Memory([]byte(`{"redis": {"pwd": "override"}}`))
+	c := New(defaultTestConfig, base, override)
+	conf, err := c.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.ListenOn != ":8080" || conf.Redis.MaxRetries != 3 || conf.Redis.Pwd != "override" || len(conf.Redis.Addr) != 1 {
+		t.Fatalf("unexpected config %+v %+v", conf, conf.Redis)
+	}
+
+	ch := make(chan *testConfig, 1)
+	cancel := c.Subscribe(func(old, new *testConfig) {
+		ch <- new
+	})
+	base.Set([]byte("listenOn: \":9090\"\nredis:\n  maxRetries: 5\n"))
+	select {
+	case conf = <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("no notify")
+	}
+	if conf.ListenOn != ":9090" || conf.Redis.MaxRetries != 5 || conf.Redis.Pwd != "override" || len(conf.Redis.Addr) != 0 {
+		t.Fatalf("unexpected config %+v %+v", conf, conf.Redis)
+	}
+
+	// 校验失败保留旧配置
+	base.Set([]byte("listenOn: \"\"\n"))
+	if c.Get().ListenOn != ":9090" {
+		t.Fatalf("invalid config applied")
+	}
+	select {
+	case <-ch:
+		t.Fatal("notify on invalid config")
+	default:
+	}
+
+	cancel()
+	c.Close()
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.yaml")
+	if err := os.WriteFile(path, []byte("listenOn: \":1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := New(defaultTestConfig, File(path, 10*time.Millisecond), File(filepath.Join(t.TempDir(), "missing.yaml"), 0))
+	conf, err := c.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if conf.ListenOn != ":1" {
+		t.Fatalf("unexpected config %+v", conf)
+	}
+	ch := make(chan *testConfig, 1)
+	c.Subscribe(func(old, new *testConfig) {
+		ch <- new
+	})
+	if err = os.WriteFile(path, []byte("listenOn: \":2\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case conf = <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notify")
+	}
+	if conf.ListenOn != ":2" {
+		t.Fatalf("unexpected config %+v", conf)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	dst := map[string]interface{}{"a": map[string]interface{}{"b": 1, "c": 2}, "d": 1}
+	Merge(dst, map[string]interface{}{"a": map[string]interface{}{"b": 3}, "d": map[string]interface{}{"e": 1}})
+	a := dst["a"].(map[string]interface{})
+	if a["b"] != 3 || a["c"] != 2 {
+		t.Fatalf("unexpected merge %+v", dst)
+	}
+	if _, ok := dst["d"].(map[string]interface{}); !ok {
+		t.Fatalf("unexpected merge %+v", dst)
+	}
+}
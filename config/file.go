@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSource 本地文件, 按修改时间和内容轮询变化
+type FileSource struct {
+	path     string
+	interval time.Duration
+}
+
+// File interval 为轮询间隔, 为0时默认5s
+func File(path string, interval time.Duration) *FileSource {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &FileSource{path: path, interval: interval}
+}
+
+func (f *FileSource) Read() ([]byte, error) {
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (f *FileSource) Watch(ctx context.Context, onChange func(data []byte)) error {
+	last, _ := f.Read()
+	var modTime time.Time
+	if info, err := os.Stat(f.path); err == nil {
+		modTime = info.ModTime()
+	}
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(f.path)
+			if err == nil && info.ModTime().Equal(modTime) {
+				continue
+			}
+			if err == nil {
+				modTime = info.ModTime()
+			}
+			data, err := f.Read()
+			if err != nil || bytes.Equal(data, last) {
+				continue
+			}
+			last = data
+			onChange(data)
+		}
+	}()
+	return nil
+}
+
+// MemorySource 内存配置, Set 时通知变化, 可用于默认值层或测试
+type MemorySource struct {
+	mtx  sync.Mutex
+	data []byte
+	subs []func(data []byte)
+}
+
+func Memory(data []byte) *MemorySource {
+	return &MemorySource{data: data}
+}
+
+func (m *MemorySource) Read() ([]byte, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.data, nil
+}
+
+func (m *MemorySource) Watch(ctx context.Context, onChange func(data []byte)) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.subs = append(m.subs, onChange)
+	idx := len(m.subs) - 1
+	go func() {
+		<-ctx.Done()
+		m.mtx.Lock()
+		defer m.mtx.Unlock()
+		m.subs[idx] = nil
+	}()
+	return nil
+}
+
+// Set 更新内容, 同步通知监听者
+func (m *MemorySource) Set(data []byte) {
+	m.mtx.Lock()
+	m.data = data
+	subs := make([]func(data []byte), 0, len(m.subs))
+	for _, fn := range m.subs {
+		if fn != nil {
+			subs = append(subs, fn)
+		}
+	}
+	m.mtx.Unlock()
+	for _, fn := range subs {
+		fn(data)
+	}
+}
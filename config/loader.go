@@ -0,0 +1,475 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type LoadOptions struct {
+	Files  []string // 配置文件, 按扩展名解析 yaml/yml/json/toml, 后面的覆盖前面的
+	Prefix string   // 环境变量和命令行参数的前缀, 为空时不加前缀
+	Env    bool     // 是否读取环境变量, 字段 MaxIdleConns 对应 PREFIX_MAX_IDLE_CONNS
+	Args   []string // 命令行参数, 字段 MaxIdleConns 对应 --prefix.max-idle-conns, 未知参数忽略
+}
+
+type LoadOption func(*LoadOptions)
+
+// Files sets the config files to load
+func Files(paths ...string) LoadOption {
+	return func(o *LoadOptions) {
+		o.Files = append(o.Files, paths...)
+	}
+}
+
+// Env enables PREFIX_FIELD environment overrides
+func Env(prefix string) LoadOption {
+	return func(o *LoadOptions) {
+		o.Env = true
+		o.Prefix = prefix
+	}
+}
+
+// Prefix sets the prefix of environment variables and flags
+func Prefix(prefix string) LoadOption {
+	return func(o *LoadOptions) {
+		o.Prefix = prefix
+	}
+}
+
+// Args sets the command line arguments, usually os.Args[1:]
+func Args(args []string) LoadOption {
+	return func(o *LoadOptions) {
+		o.Args = args
+	}
+}
+
+// Load 按 v 中已有值(通常来自 DefaultXxxConfig) < 文件 < 环境变量 < 命令行 的顺序填充 v, v 必须是结构体指针.
+// 字段按名称忽略大小写和 _- 匹配, 有 yaml/json tag 时也匹配 tag; 函数/通道/接口字段忽略
+func Load(v interface{}, opts ...LoadOption) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("param is err")
+	}
+	o := LoadOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	for _, path := range o.Files {
+		m, err := ParseFile(path)
+		if err != nil {
+			return err
+		}
+		if err = Assign(m, v); err != nil {
+			return fmt.Errorf("config file(%s) %+v", path, err)
+		}
+	}
+	if o.Env {
+		if err := Assign(envOverlay(rv.Elem().Type(), o.Prefix), v); err != nil {
+			return fmt.Errorf("config env %+v", err)
+		}
+	}
+	if len(o.Args) > 0 {
+		m, err := argsOverlay(rv.Elem().Type(), o.Prefix, o.Args)
+		if err != nil {
+			return err
+		}
+		if err = Assign(m, v); err != nil {
+			return fmt.Errorf("config args %+v", err)
+		}
+	}
+	return nil
+}
+
+// LoadAs 以 defaults 的返回值为默认值加载
+func LoadAs[T any](defaults func() *T, opts ...LoadOption) (*T, error) {
+	v := new(T)
+	if defaults != nil {
+		if def := defaults(); def != nil {
+			v = def
+		}
+	}
+	if err := Load(v, opts...); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ParseFile 按扩展名解析配置文件
+func ParseFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		m, err = ParseToml(data)
+	} else {
+		m, err = Parse(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file(%s) parse err(%+v)", path, err)
+	}
+	return m, nil
+}
+
+// NameDecoder 按字段名匹配的 Decoder, 用于没有 tag 的结构体
+func NameDecoder(m map[string]interface{}, v interface{}) error {
+	return Assign(m, v)
+}
+
+// Assign 将 m 按字段名填充到结构体指针 v, m 中没有的字段保持不变
+func Assign(m map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("param is err")
+	}
+	return setValue(rv.Elem(), m, "")
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func normalizeName(s string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(s))
+}
+
+// fieldNames 字段可匹配的名称
+func fieldNames(f reflect.StructField) []string {
+	names := []string{normalizeName(f.Name)}
+	for _, tag := range []string{"yaml", "json"} {
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if len(name) > 0 && name != "-" {
+			names = append(names, normalizeName(name))
+		}
+	}
+	return names
+}
+
+// skipped 不能从配置文本设置的类型
+func skipped(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+func setStruct(v reflect.Value, m map[string]interface{}, path string) error {
+	keys := make(map[string]interface{}, len(m))
+	for k, val := range m {
+		keys[normalizeName(k)] = val
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || skipped(f.Type) {
+			continue
+		}
+		for _, name := range fieldNames(f) {
+			val, ok := keys[name]
+			if !ok {
+				continue
+			}
+			if err := setValue(v.Field(i), val, path+f.Name); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, x interface{}, path string) error {
+	if x == nil {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), x, path)
+	case reflect.Struct:
+		m, ok := x.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("field(%s) want object, got %T", path, x)
+		}
+		return setStruct(v, m, path+".")
+	case reflect.Slice, reflect.Array:
+		var items []interface{}
+		switch t := x.(type) {
+		case []interface{}:
+			items = t
+		case string:
+			// 环境变量和命令行中用逗号分隔
+			for _, s := range strings.Split(t, ",") {
+				if s = strings.TrimSpace(s); len(s) > 0 {
+					items = append(items, s)
+				}
+			}
+		default:
+			items = []interface{}{x}
+		}
+		if v.Kind() == reflect.Array {
+			if len(items) > v.Len() {
+				return fmt.Errorf("field(%s) too many items", path)
+			}
+			for i, item := range items {
+				if err := setValue(v.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(list.Index(i), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+		return nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("field(%s) map key must be string", path)
+		}
+		m, ok := x.(map[string]interface{})
+		if !ok {
+			s, ok := x.(string)
+			if !ok {
+				return fmt.Errorf("field(%s) want object, got %T", path, x)
+			}
+			// k1=v1,k2=v2
+			m = make(map[string]interface{})
+			for _, kv := range strings.Split(s, ",") {
+				if pair := strings.SplitN(kv, "=", 2); len(pair) == 2 {
+					m[strings.TrimSpace(pair[0])] = strings.TrimSpace(pair[1])
+				}
+			}
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for k, val := range m {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if old := v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())); old.IsValid() {
+				elem.Set(old)
+			}
+			if err := setValue(elem, val, path+"."+k); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	}
+	var s string
+	switch t := x.(type) {
+	case string:
+		s = t
+	case float64:
+		// json 数字均为 float64, 整数值按整数格式化
+		s = strconv.FormatFloat(t, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		return fmt.Errorf("field(%s) want scalar, got %T", path, x)
+	default:
+		s = fmt.Sprint(x)
+	}
+	if err := setScalar(v, s); err != nil {
+		return fmt.Errorf("field(%s) %+v", path, err)
+	}
+	return nil
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		if d, err := time.ParseDuration(s); err == nil {
+			v.SetInt(int64(d))
+			return nil
+		}
+		// 纯数字按纳秒
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// leaf 可覆盖的字段, path 为字段名路径
+type leaf struct {
+	path []string
+	bool bool
+}
+
+// leaves 展开嵌套结构体的字段, 切片/map 作为整体
+func leaves(t reflect.Type, path []string, depth int) []leaf {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || depth > 8 {
+		return nil
+	}
+	var list []leaf
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || skipped(f.Type) {
+			continue
+		}
+		p := append(append([]string{}, path...), f.Name)
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft != durationType {
+			list = append(list, leaves(ft, p, depth+1)...)
+			continue
+		}
+		list = append(list, leaf{path: p, bool: ft.Kind() == reflect.Bool})
+	}
+	return list
+}
+
+// splitWords 按驼峰拆分, HTTPServer -> HTTP Server
+func splitWords(s string) []string {
+	var words []string
+	rs := []rune(s)
+	start := 0
+	for i := 1; i < len(rs); i++ {
+		prev, cur := rs[i-1], rs[i]
+		next := rune(0)
+		if i+1 < len(rs) {
+			next = rs[i+1]
+		}
+		if unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && unicode.IsLower(next))) {
+			words = append(words, string(rs[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(rs[start:]))
+}
+
+// EnvName 字段路径对应的环境变量名, Redis.MaxRetries -> PREFIX_REDIS_MAX_RETRIES
+func EnvName(prefix string, path ...string) string {
+	var parts []string
+	if len(prefix) > 0 {
+		parts = append(parts, strings.ToUpper(prefix))
+	}
+	for _, p := range path {
+		parts = append(parts, strings.ToUpper(strings.Join(splitWords(p), "_")))
+	}
+	return strings.Join(parts, "_")
+}
+
+// FlagName 字段路径对应的命令行参数名, Redis.MaxRetries -> prefix.redis.max-retries
+func FlagName(prefix string, path ...string) string {
+	var parts []string
+	if len(prefix) > 0 {
+		parts = append(parts, strings.ToLower(prefix))
+	}
+	for _, p := range path {
+		parts = append(parts, strings.ToLower(strings.Join(splitWords(p), "-")))
+	}
+	return strings.Join(parts, ".")
+}
+
+func setPath(m map[string]interface{}, path []string, val interface{}) {
+	for _, p := range path[:len(path)-1] {
+		sub, ok := m[p].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[p] = sub
+		}
+		m = sub
+	}
+	m[path[len(path)-1]] = val
+}
+
+func envOverlay(t reflect.Type, prefix string) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, l := range leaves(t, nil, 0) {
+		if val, ok := os.LookupEnv(EnvName(prefix, l.path...)); ok {
+			setPath(m, l.path, val)
+		}
+	}
+	return m
+}
+
+// argsOverlay 支持 --name=value, --name value, 布尔字段可省略值
+func argsOverlay(t reflect.Type, prefix string, args []string) (map[string]interface{}, error) {
+	fields := make(map[string]leaf)
+	for _, l := range leaves(t, nil, 0) {
+		fields[FlagName(prefix, l.path...)] = l
+	}
+	m := make(map[string]interface{})
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		val, hasVal := "", false
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name, val, hasVal = name[:idx], name[idx+1:], true
+		}
+		l, ok := fields[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		if !hasVal {
+			if l.bool {
+				val = "true"
+			} else if i+1 < len(args) {
+				i++
+				val = args[i]
+			} else {
+				return nil, fmt.Errorf("config flag(%s) missing value", name)
+			}
+		}
+		setPath(m, l.path, val)
+	}
+	return m, nil
+}
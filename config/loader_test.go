@@ -0,0 +1,145 @@
+package config
+
+import (
+	"github.com/y1015860449/gotoolkit/log/zaplog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testMq struct {
+	Addrs    []string
+	Retry    int
+	Timeout  time.Duration
+	Labels   map[string]string
+	Handler  func()
+	Redis    *testRedis
+	Disabled bool
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := writeFile(t, "log.yaml", "logPath: ./logs/a.log\nmax_size: 10\nshowLine: false\n")
+	jsonFile := writeFile(t, "log.json", `{"LogLevel": "info", "maxSize": 20}`)
+	t.Setenv("LOG_MAX_BACKUPS", "3")
+	t.Setenv("LOG_SERVER_NAME", "env")
+	conf, err := LoadAs(zaplog.DefaultConfig,
+		Files(yamlFile, jsonFile),
+		Env("log"),
+		Args([]string{"-v", "--log.server-name=flag", "--log.compress", "--other", "x", "--log.max-age", "30"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := zaplog.DefaultConfig()
+	want.LogPath = "./logs/a.log"
+	want.MaxSize = 20
+	want.ShowLine = false
+	want.LogLevel = "info"
+	want.MaxBackups = 3
+	want.ServerName = "flag"
+	want.Compress = true
+	want.MaxAge = 30
+	if !reflect.DeepEqual(conf, want) {
+		t.Fatalf("got %+v, want %+v", conf, want)
+	}
+}
+
+func TestLoadToml(t *testing.T) {
+	tomlFile := writeFile(t, "mq.toml", `
+# rocketmq
+addrs = [
+  "127.0.0.1:9876", # name server
+  "127.0.0.2:9876",
+]
+retry = 0x10
+timeout = "1m30s"
+labels = { zone = "a", "env" = 'prod' }
+
+[redis]
+addr = ["127.0.0.1:6379"]
+pwd = "p#w\"d"
+max_retries = 1_000
+`)
+	t.Setenv("MQ_REDIS_PWD", "env")
+	t.Setenv("MQ_LABELS", "zone=b")
+	conf := &testMq{Retry: 3, Handler: func() {}}
+	if err := Load(conf, Files(tomlFile), Env("MQ"), Args([]string{"--mq.redis.addr", "a:1,b:2"})); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(conf.Addrs, []string{"127.0.0.1:9876", "127.0.0.2:9876"}) || conf.Retry != 16 || conf.Timeout != 90*time.Second {
+		t.Fatalf("unexpected config %+v", conf)
+	}
+	if !reflect.DeepEqual(conf.Labels, map[string]string{"zone": "b", "env": "prod"}) {
+		t.Fatalf("unexpected labels %+v", conf.Labels)
+	}
+	if conf.Redis == nil || conf.Redis.Pwd != "env" || conf.Redis.MaxRetries != 1000 || !reflect.DeepEqual(conf.Redis.Addr, []string{"a:1", "b:2"}) {
+		t.Fatalf("unexpected redis %+v", conf.Redis)
+	}
+	if conf.Handler == nil {
+		t.Fatal("func field cleared")
+	}
+}
+
+func TestParseToml(t *testing.T) {
+	m, err := ParseToml([]byte(`
+title = "t"
+a.b = 1
+desc = """
+line1
+line2"""
+day = 2024-05-01
+at = 2024-05-01T08:00:00Z
+[[servers]]
+name = "s1"
+[[servers]]
+name = "s2"
+[servers.meta]
+weight = 1.5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"title": "t",
+		"a":     map[string]interface{}{"b": int64(1)},
+		"desc":  "line1\nline2",
+		"day":   "2024-05-01",
+		"at":    "2024-05-01T08:00:00Z",
+		"servers": []interface{}{
+			map[string]interface{}{"name": "s1"},
+			map[string]interface{}{"name": "s2", "meta": map[string]interface{}{"weight": 1.5}},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("got %+v, want %+v", m, want)
+	}
+	if _, err = ParseToml([]byte("a = \"unclosed\n")); err == nil {
+		t.Fatal("want error")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	cases := map[string][]string{
+		"P_MAX_IDLE_CONNS":   {"MaxIdleConns"},
+		"P_HTTP_SERVER_ADDR": {"HTTPServer", "Addr"},
+		"P_CLIENT_ID":        {"ClientId"},
+	}
+	for want, path := range cases {
+		if got := EnvName("p", path...); got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+	if got := FlagName("", "Redis", "MaxRetries"); got != "redis.max-retries" {
+		t.Fatalf("got %s", got)
+	}
+}
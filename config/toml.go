@@ -0,0 +1,46 @@
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+	"time"
+)
+
+// ParseToml 解析 toml, 日期时间按原格式转为字符串, 数组表转为 []interface{}, 与 Parse 的结果结构一致
+func ParseToml(data []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &m); err != nil {
+		return nil, err
+	}
+	return normalizeToml(m).(map[string]interface{}), nil
+}
+
+func normalizeToml(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeToml(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeToml(e)
+		}
+	case []map[string]interface{}:
+		list := make([]interface{}, 0, len(t))
+		for _, e := range t {
+			list = append(list, normalizeToml(e))
+		}
+		return list
+	case time.Time:
+		// 不带时区的日期、时间由 toml 用特殊名字的时区标记
+		switch t.Location().String() {
+		case "date-local":
+			return t.Format("2006-01-02")
+		case "time-local":
+			return t.Format("15:04:05.999999999")
+		case "datetime-local":
+			return t.Format("2006-01-02T15:04:05.999999999")
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	return v
+}
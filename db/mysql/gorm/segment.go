@@ -0,0 +1,51 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// SegmentStore 号段模式的 mysql 存储, 实现 idMaker.SegmentStore
+// tag 不存在时自动插入, max_id 可预先设置为起始值, 表结构:
+//
+//	CREATE TABLE `id_segment` (
+//	  `biz_tag` varchar(128) NOT NULL,
+//	  `max_id` bigint NOT NULL DEFAULT 0,
+//	  `step` int NOT NULL DEFAULT 0,
+//	  `update_time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+//	  PRIMARY KEY (`biz_tag`)
+//	) ENGINE=InnoDB;
+type SegmentStore struct {
+	db     *gorm.DB
+	upsert string
+	query  string
+}
+
+func NewSegmentStore(db *gorm.DB, table string) (*SegmentStore, error) {
+	if db == nil || len(table) <= 0 {
+		return nil, errors.New("param is err")
+	}
+	return &SegmentStore{
+		db: db,
+		upsert: fmt.Sprintf("INSERT INTO `%s` (`biz_tag`, `max_id`, `step`) VALUES (?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `max_id` = `max_id` + VALUES(`step`), `step` = VALUES(`step`)", table),
+		query: fmt.Sprintf("SELECT `max_id` FROM `%s` WHERE `biz_tag` = ?", table),
+	}, nil
+}
+
+// Reserve 在同一事务中累加并读取 max_id, 行锁保证多实例区间不重叠
+func (s *SegmentStore) Reserve(ctx context.Context, tag string, step int64) (int64, int64, error) {
+	var maxId int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(s.upsert, tag, step, step).Error; err != nil {
+			return err
+		}
+		return tx.Raw(s.query, tag).Scan(&maxId).Error
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return maxId - step + 1, maxId, nil
+}
@@ -0,0 +1,58 @@
+package xorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-xorm/xorm"
+	"strconv"
+)
+
+// SegmentStore 号段模式的 mysql 存储, 实现 idMaker.SegmentStore, 表结构见 db/mysql/gorm.SegmentStore
+type SegmentStore struct {
+	db     interface{ NewSession() *xorm.Session }
+	upsert string
+	query  string
+}
+
+// NewSegmentStore db 为 Connect 或 GroupConnect 返回的连接
+func NewSegmentStore(db interface{ NewSession() *xorm.Session }, table string) (*SegmentStore, error) {
+	if db == nil || len(table) <= 0 {
+		return nil, errors.New("param is err")
+	}
+	return &SegmentStore{
+		db: db,
+		upsert: fmt.Sprintf("INSERT INTO `%s` (`biz_tag`, `max_id`, `step`) VALUES (?, ?, ?) "+
+			"ON DUPLICATE KEY UPDATE `max_id` = `max_id` + VALUES(`step`), `step` = VALUES(`step`)", table),
+		query: fmt.Sprintf("SELECT `max_id` FROM `%s` WHERE `biz_tag` = ?", table),
+	}, nil
+}
+
+// Reserve 在同一事务中累加并读取 max_id, 行锁保证多实例区间不重叠
+func (s *SegmentStore) Reserve(ctx context.Context, tag string, step int64) (int64, int64, error) {
+	session := s.db.NewSession().Context(ctx)
+	defer session.Close()
+	if err := session.Begin(); err != nil {
+		return 0, 0, err
+	}
+	if _, err := session.Exec(s.upsert, tag, step, step); err != nil {
+		_ = session.Rollback()
+		return 0, 0, err
+	}
+	rows, err := session.QueryString(s.query, tag)
+	if err != nil {
+		_ = session.Rollback()
+		return 0, 0, err
+	}
+	if err = session.Commit(); err != nil {
+		return 0, 0, err
+	}
+	if len(rows) != 1 {
+		return 0, 0, errors.New("segment tag not found")
+	}
+	maxId, err := strconv.ParseInt(rows[0]["max_id"], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return maxId - step + 1, maxId, nil
+}
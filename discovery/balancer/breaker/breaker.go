@@ -0,0 +1,230 @@
+package breaker
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/grpclog"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Name is the name of breaker balancer.
+const Name = "bln_breaker"
+
+type Config struct {
+	Window          time.Duration // 统计窗口, 默认10s
+	MinRequests     int64         // 窗口内最少请求数, 达到后才判断, 默认20
+	ErrorRate       float64       // 错误率阈值, 默认0.5
+	SlowDuration    time.Duration // 慢调用阈值, 为0不统计
+	SlowRate        float64       // 慢调用比例阈值, 默认0.5
+	EjectDuration   time.Duration // 驱逐冷却时间, 默认30s
+	MaxEjectPercent int           // 最多驱逐实例百分比, 超过则忽略熔断, 默认50
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Window:          10 * time.Second,
+		MinRequests:     20,
+		ErrorRate:       0.5,
+		SlowRate:        0.5,
+		EjectDuration:   30 * time.Second,
+		MaxEjectPercent: 50,
+	}
+}
+
+var (
+	logger = grpclog.Component("breaker")
+
+	confMtx sync.RWMutex
+	conf    = DefaultConfig()
+
+	// 存活的 balancer, Close 时移除
+	pickerMtx sync.Mutex
+	pickers   = make(map[*breakerPickerBuilder]struct{})
+)
+
+// SetConfig 设置熔断参数, 未设置的字段使用默认值
+func SetConfig(c *Config) {
+	if c == nil {
+		return
+	}
+	def := DefaultConfig()
+	tmp := *c
+	if tmp.Window <= 0 {
+		tmp.Window = def.Window
+	}
+	if tmp.MinRequests <= 0 {
+		tmp.MinRequests = def.MinRequests
+	}
+	if tmp.ErrorRate <= 0 {
+		tmp.ErrorRate = def.ErrorRate
+	}
+	if tmp.SlowRate <= 0 {
+		tmp.SlowRate = def.SlowRate
+	}
+	if tmp.EjectDuration <= 0 {
+		tmp.EjectDuration = def.EjectDuration
+	}
+	if tmp.MaxEjectPercent <= 0 {
+		tmp.MaxEjectPercent = def.MaxEjectPercent
+	}
+	confMtx.Lock()
+	conf = &tmp
+	confMtx.Unlock()
+}
+
+func getConfig() *Config {
+	confMtx.RLock()
+	defer confMtx.RUnlock()
+	return conf
+}
+
+// GetState 获取实例的熔断状态, 多个连接使用同一实例时返回最严重的状态
+func GetState(addr string) State {
+	return States()[addr]
+}
+
+// States 获取所有实例的熔断状态
+func States() map[string]State {
+	pickerMtx.Lock()
+	defer pickerMtx.Unlock()
+	states := make(map[string]State)
+	for pb := range pickers {
+		pb.mu.Lock()
+		for _, item := range pb.circuits {
+			if st := item.c.State(); severity(st) >= severity(states[item.addr]) {
+				states[item.addr] = st
+			}
+		}
+		pb.mu.Unlock()
+	}
+	return states
+}
+
+func severity(s State) int {
+	switch s {
+	case StateOpen:
+		return 2
+	case StateHalfOpen:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type builder struct{}
+
+func (builder) Name() string {
+	return Name
+}
+
+// Build 每个 ClientConn 使用独立的熔断器集合, 实例被移除时删除对应的熔断器
+func (builder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &breakerPickerBuilder{circuits: make(map[balancer.SubConn]*addrCircuit)}
+	pickerMtx.Lock()
+	pickers[pb] = struct{}{}
+	pickerMtx.Unlock()
+	b := base.NewBalancerBuilder(Name, pb, base.Config{HealthCheck: true}).Build(&clientConn{ClientConn: cc, pb: pb}, opts)
+	return &breakerBalancer{Balancer: b, pb: pb}
+}
+
+func init() {
+	balancer.Register(builder{})
+}
+
+type breakerBalancer struct {
+	balancer.Balancer
+	pb *breakerPickerBuilder
+}
+
+func (b *breakerBalancer) ExitIdle() {
+	if ei, ok := b.Balancer.(balancer.ExitIdler); ok {
+		ei.ExitIdle()
+	}
+}
+
+func (b *breakerBalancer) Close() {
+	b.Balancer.Close()
+	pickerMtx.Lock()
+	delete(pickers, b.pb)
+	pickerMtx.Unlock()
+}
+
+// clientConn 拦截 RemoveSubConn, 实例被移除时删除熔断器
+type clientConn struct {
+	balancer.ClientConn
+	pb *breakerPickerBuilder
+}
+
+func (cc *clientConn) RemoveSubConn(sc balancer.SubConn) {
+	cc.pb.mu.Lock()
+	delete(cc.pb.circuits, sc)
+	cc.pb.mu.Unlock()
+	cc.ClientConn.RemoveSubConn(sc)
+}
+
+type addrCircuit struct {
+	addr string
+	c    *circuit
+}
+
+// breakerPickerBuilder 熔断状态按 SubConn 保存, picker 重建后仍然有效
+type breakerPickerBuilder struct {
+	mu       sync.Mutex
+	circuits map[balancer.SubConn]*addrCircuit
+}
+
+func (pb *breakerPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	logger.Infof("breakerPicker: Build called with info: %v", info)
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	scs := make([]*subConn, 0, len(info.ReadySCs))
+	for sc, scInfo := range info.ReadySCs {
+		item, ok := pb.circuits[sc]
+		if !ok {
+			item = &addrCircuit{addr: scInfo.Address.Addr, c: newCircuit()}
+			pb.circuits[sc] = item
+		}
+		scs = append(scs, &subConn{sc: sc, c: item.c})
+	}
+	return &breakerPicker{
+		subConns: scs,
+	}
+}
+
+type subConn struct {
+	sc balancer.SubConn
+	c  *circuit
+}
+
+type breakerPicker struct {
+	subConns []*subConn
+}
+
+func (p *breakerPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	c := getConfig()
+	now := time.Now()
+	allowed := make([]*subConn, 0, len(p.subConns))
+	for _, item := range p.subConns {
+		if item.c.available(now) {
+			allowed = append(allowed, item)
+		}
+	}
+	// 驱逐过多时忽略熔断, 避免剩余实例被压垮
+	ejected := len(p.subConns) - len(allowed)
+	if len(allowed) == 0 || ejected*100 > len(p.subConns)*c.MaxEjectPercent {
+		allowed = p.subConns
+	}
+	item := allowed[rand.Int()%len(allowed)]
+	item.c.acquire(now)
+	return balancer.PickResult{
+		SubConn: item.sc,
+		Done: func(info balancer.DoneInfo) {
+			item.c.record(c, info.Err, time.Since(now), time.Now())
+		},
+	}, nil
+}
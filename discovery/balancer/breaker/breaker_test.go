@@ -0,0 +1,109 @@
+package breaker
+
+import (
+	"errors"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestCircuit(t *testing.T) {
+	conf := DefaultConfig()
+	conf.MinRequests = 4
+	conf.EjectDuration = time.Second
+	c := newCircuit()
+	now := time.Now()
+
+	unavailable := status.Error(codes.Unavailable, "down")
+	for i := 0; i < 4; i++ {
+		c.record(conf, errors.New("business"), time.Millisecond, now)
+	}
+	if c.State() != StateClosed {
+		t.Fatalf("business errors must not trip, state(%v)", c.State())
+	}
+
+	now = now.Add(conf.Window + time.Second)
+	for i := 0; i < 4; i++ {
+		c.record(conf, unavailable, time.Millisecond, now)
+	}
+	if c.State() != StateOpen || c.available(now) {
+		t.Fatalf("want open, state(%v)", c.State())
+	}
+
+	now = now.Add(conf.EjectDuration)
+	if !c.available(now) {
+		t.Fatalf("want available after eject duration")
+	}
+	c.acquire(now)
+	if c.State() != StateHalfOpen || c.available(now) {
+		t.Fatalf("want half-open with probe in flight, state(%v)", c.State())
+	}
+	c.record(conf, unavailable, time.Millisecond, now)
+	if c.State() != StateOpen {
+		t.Fatalf("failed probe want open, state(%v)", c.State())
+	}
+
+	now = now.Add(conf.EjectDuration)
+	c.acquire(now)
+	c.record(conf, nil, time.Millisecond, now)
+	if c.State() != StateClosed {
+		t.Fatalf("successful probe want closed, state(%v)", c.State())
+	}
+}
+
+func TestSlowCall(t *testing.T) {
+	conf := DefaultConfig()
+	conf.MinRequests = 2
+	conf.SlowDuration = 100 * time.Millisecond
+	c := newCircuit()
+	now := time.Now()
+	c.record(conf, nil, 200*time.Millisecond, now)
+	c.record(conf, nil, 200*time.Millisecond, now)
+	if c.State() != StateOpen {
+		t.Fatalf("slow calls want open, state(%v)", c.State())
+	}
+}
+
+type fakeClientConn struct {
+	balancer.ClientConn
+}
+
+func (*fakeClientConn) RemoveSubConn(balancer.SubConn) {}
+
+type fakeSubConn struct {
+	balancer.SubConn
+}
+
+func TestRemoveSubConn(t *testing.T) {
+	pb := &breakerPickerBuilder{circuits: make(map[balancer.SubConn]*addrCircuit)}
+	pickerMtx.Lock()
+	pickers[pb] = struct{}{}
+	pickerMtx.Unlock()
+	defer func() {
+		pickerMtx.Lock()
+		delete(pickers, pb)
+		pickerMtx.Unlock()
+	}()
+
+	sc := &fakeSubConn{}
+	pb.Build(base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+		sc: {Address: resolver.Address{Addr: "10.0.0.1:80"}},
+	}})
+	pb.circuits[sc].c.trip(DefaultConfig(), time.Now())
+	if GetState("10.0.0.1:80") != StateOpen {
+		t.Fatalf("want open, state(%v)", GetState("10.0.0.1:80"))
+	}
+
+	cc := &clientConn{ClientConn: &fakeClientConn{}, pb: pb}
+	cc.RemoveSubConn(sc)
+	if len(pb.circuits) != 0 {
+		t.Fatalf("circuit not removed")
+	}
+	if _, ok := States()["10.0.0.1:80"]; ok {
+		t.Fatalf("removed instance still reported")
+	}
+}
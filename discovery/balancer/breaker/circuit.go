@@ -0,0 +1,139 @@
+package breaker
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+// State 熔断器状态
+type State int32
+
+const (
+	StateClosed   State = iota // 正常
+	StateOpen                  // 熔断, 实例被驱逐
+	StateHalfOpen              // 半开, 放行一个探测请求
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuit 单个实例的熔断器
+type circuit struct {
+	mu          sync.Mutex
+	state       State
+	windowStart time.Time
+	total       int64
+	failures    int64
+	slows       int64
+	openUntil   time.Time
+	probing     bool
+}
+
+func newCircuit() *circuit {
+	return &circuit{windowStart: time.Now()}
+}
+
+func (c *circuit) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// available 是否可以参与负载均衡, 不改变状态
+func (c *circuit) available(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state {
+	case StateOpen:
+		return !now.Before(c.openUntil)
+	case StateHalfOpen:
+		return !c.probing
+	default:
+		return true
+	}
+}
+
+// acquire 选中实例时调用, 冷却期结束的实例转为半开并占用探测名额
+func (c *circuit) acquire(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state == StateOpen && !now.Before(c.openUntil) {
+		c.state = StateHalfOpen
+		c.probing = false
+	}
+	if c.state == StateHalfOpen {
+		c.probing = true
+	}
+}
+
+// record 记录一次调用结果
+func (c *circuit) record(conf *Config, err error, cost time.Duration, now time.Time) {
+	failed := isFailure(err)
+	slow := conf.SlowDuration > 0 && cost >= conf.SlowDuration
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch c.state {
+	case StateOpen:
+		return
+	case StateHalfOpen:
+		c.probing = false
+		if failed || slow {
+			c.trip(conf, now)
+		} else {
+			c.reset(now)
+		}
+		return
+	}
+	if now.Sub(c.windowStart) > conf.Window {
+		c.reset(now)
+	}
+	c.total++
+	if failed {
+		c.failures++
+	}
+	if slow {
+		c.slows++
+	}
+	if c.total < conf.MinRequests {
+		return
+	}
+	if float64(c.failures)/float64(c.total) >= conf.ErrorRate ||
+		(conf.SlowDuration > 0 && float64(c.slows)/float64(c.total) >= conf.SlowRate) {
+		c.trip(conf, now)
+	}
+}
+
+func (c *circuit) trip(conf *Config, now time.Time) {
+	c.state = StateOpen
+	c.openUntil = now.Add(conf.EjectDuration)
+	logger.Infof("breaker: circuit open until %v", c.openUntil)
+}
+
+func (c *circuit) reset(now time.Time) {
+	c.state = StateClosed
+	c.windowStart = now
+	c.total, c.failures, c.slows = 0, 0, 0
+}
+
+// isFailure 只统计服务端不可用类错误, 业务错误不计入
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,55 @@
+package zone
+
+import "google.golang.org/grpc/resolver"
+
+// ZoneKey 元数据中表示机房/可用区的字段
+const ZoneKey = "zone"
+
+type metadataKey struct{}
+
+// Metadata 服务实例元数据, 由各 resolver 写入 resolver.Address
+type Metadata map[string]string
+
+// Equal 实现 attributes 的比较, map 本身不可比较
+func (m Metadata) Equal(o interface{}) bool {
+	om, ok := o.(Metadata)
+	if !ok || len(m) != len(om) {
+		return false
+	}
+	for k, v := range m {
+		if ov, ok := om[k]; !ok || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Zone 实例所在区
+func (m Metadata) Zone() string {
+	return m[ZoneKey]
+}
+
+// Match 判断实例是否包含全部标签
+func (m Metadata) Match(tags map[string]string) bool {
+	for k, v := range tags {
+		if m[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// SetMetadata 将实例元数据附加到地址上
+func SetMetadata(addr resolver.Address, md map[string]string) resolver.Address {
+	if len(md) == 0 {
+		return addr
+	}
+	addr.Attributes = addr.Attributes.WithValue(metadataKey{}, Metadata(md))
+	return addr
+}
+
+// GetMetadata 获取地址上的实例元数据
+func GetMetadata(addr resolver.Address) Metadata {
+	md, _ := addr.Attributes.Value(metadataKey{}).(Metadata)
+	return md
+}
@@ -0,0 +1,137 @@
+package zone
+
+import (
+	"context"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/status"
+	"math/rand"
+)
+
+const Name = "bln_zone"
+
+type tagsKey struct{}
+
+// WithTags 按请求指定标签, 覆盖 Config.Tags
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	return context.WithValue(ctx, tagsKey{}, tags)
+}
+
+// Fallback 本地节点不足时的回退策略
+type Fallback int
+
+const (
+	FallbackAll  Fallback = iota // 回退到全部可用节点
+	FallbackTags                 // 回退到其他区中标签匹配的节点
+	FallbackNone                 // 不回退, 只使用本地节点, 没有时请求直接返回 Unavailable
+)
+
+type Config struct {
+	Zone     string            // 调用方所在区, 为空则不区分区
+	Tags     map[string]string // 调用方要求的标签, 例: {"version": "canary"}
+	MinReady int               // 本地可用节点少于该值时触发回退, 默认1
+	Fallback Fallback          // 回退策略
+}
+
+var logger = grpclog.Component("zone")
+
+// NewBuilder 创建使用配置 c 的 balancer builder, c 为空时不区分区和标签
+func NewBuilder(name string, c *Config) balancer.Builder {
+	conf := Config{}
+	if c != nil {
+		conf = *c
+	}
+	if conf.MinReady <= 0 {
+		conf.MinReady = 1
+	}
+	return base.NewBalancerBuilder(name, &zonePickerBuilder{conf: conf}, base.Config{HealthCheck: true})
+}
+
+// Register 以 name 注册使用配置 c 的 balancer, 需在 Dial 之前调用, 不同配置使用不同的 name;
+// Name 注册的是不区分区和标签的默认配置
+func Register(name string, c *Config) {
+	balancer.Register(NewBuilder(name, c))
+}
+
+func init() {
+	Register(Name, nil)
+}
+
+type zonePickerBuilder struct {
+	conf Config
+}
+
+func (b *zonePickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	logger.Infof("zonePicker: Build called with info: %v", info)
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	scs := make([]subConn, 0, len(info.ReadySCs))
+	for sc, scInfo := range info.ReadySCs {
+		scs = append(scs, subConn{sc: sc, md: GetMetadata(scInfo.Address)})
+	}
+	return &zonePicker{
+		conf:     b.conf,
+		subConns: scs,
+	}
+}
+
+type subConn struct {
+	sc balancer.SubConn
+	md Metadata
+}
+
+type zonePicker struct {
+	conf     Config
+	subConns []subConn
+}
+
+func (p *zonePicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	c := p.conf
+	if info.Ctx != nil {
+		if tags, ok := info.Ctx.Value(tagsKey{}).(map[string]string); ok {
+			c.Tags = tags
+		}
+	}
+	scs := p.candidates(&c)
+	if len(scs) == 0 {
+		// 有可用节点但都不满足区和标签要求, 新的 picker 也不会改变结果, 直接失败而不是等待
+		return balancer.PickResult{}, status.Error(codes.Unavailable, "zone: no available sub conn matches zone and tags")
+	}
+	sc := scs[rand.Int()%len(scs)]
+	return balancer.PickResult{SubConn: sc}, nil
+}
+
+// candidates 按 本区+标签 -> 标签 -> 全部 的顺序筛选可用节点
+func (p *zonePicker) candidates(c *Config) []balancer.SubConn {
+	var local, tagged, all []balancer.SubConn
+	for _, item := range p.subConns {
+		all = append(all, item.sc)
+		if !item.md.Match(c.Tags) {
+			continue
+		}
+		tagged = append(tagged, item.sc)
+		if len(c.Zone) <= 0 || item.md.Zone() == c.Zone {
+			local = append(local, item.sc)
+		}
+	}
+	if len(local) >= c.MinReady {
+		return local
+	}
+	switch c.Fallback {
+	case FallbackTags:
+		if len(tagged) > 0 {
+			return tagged
+		}
+		return local
+	case FallbackNone:
+		return local
+	default:
+		if len(tagged) > 0 {
+			return tagged
+		}
+		return all
+	}
+}
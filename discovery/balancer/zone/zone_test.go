@@ -0,0 +1,72 @@
+package zone
+
+import (
+	"context"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+type testSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+func buildPicker(c *Config, nodes map[string]map[string]string) balancer.Picker {
+	ready := make(map[balancer.SubConn]base.SubConnInfo)
+	for name, md := range nodes {
+		addr := SetMetadata(resolver.Address{Addr: name}, md)
+		ready[&testSubConn{name: name}] = base.SubConnInfo{Address: addr}
+	}
+	conf := Config{MinReady: 1}
+	if c != nil {
+		conf = *c
+		if conf.MinReady <= 0 {
+			conf.MinReady = 1
+		}
+	}
+	return (&zonePickerBuilder{conf: conf}).Build(base.PickerBuildInfo{ReadySCs: ready})
+}
+
+func pickName(t *testing.T, p balancer.Picker, ctx context.Context) string {
+	res, err := p.Pick(balancer.PickInfo{Ctx: ctx})
+	if err != nil {
+		t.Fatalf("pick err(%v)", err)
+	}
+	return res.SubConn.(*testSubConn).name
+}
+
+func TestZonePicker(t *testing.T) {
+	nodes := map[string]map[string]string{
+		"a-stable": {ZoneKey: "a", "version": "stable"},
+		"a-canary": {ZoneKey: "a", "version": "canary"},
+		"b-stable": {ZoneKey: "b", "version": "stable"},
+	}
+
+	p := buildPicker(&Config{Zone: "a", Tags: map[string]string{"version": "stable"}}, nodes)
+	for i := 0; i < 20; i++ {
+		if name := pickName(t, p, context.Background()); name != "a-stable" {
+			t.Errorf("want a-stable, got %s", name)
+		}
+	}
+
+	ctx := WithTags(context.Background(), map[string]string{"version": "canary"})
+	if name := pickName(t, p, ctx); name != "a-canary" {
+		t.Errorf("want a-canary, got %s", name)
+	}
+
+	p = buildPicker(&Config{Zone: "c", Tags: map[string]string{"version": "stable"}, Fallback: FallbackTags}, nodes)
+	for i := 0; i < 20; i++ {
+		if name := pickName(t, p, context.Background()); name == "a-canary" {
+			t.Errorf("fallback picked untagged node %s", name)
+		}
+	}
+
+	p = buildPicker(&Config{Zone: "c", Fallback: FallbackNone}, nodes)
+	if _, err := p.Pick(balancer.PickInfo{Ctx: context.Background()}); status.Code(err) != codes.Unavailable {
+		t.Errorf("want Unavailable without local nodes, got %v", err)
+	}
+}
@@ -4,15 +4,30 @@ import "time"
 
 const schemeName = "consul"
 
+// 健康检查类型
+const (
+	CheckTTL  = "ttl"  // 由本进程定时上报状态
+	CheckGRPC = "grpc" // consul 调用 grpc.health.v1
+	CheckHTTP = "http" // consul 调用 http 接口
+)
+
 type ConsulConfig struct {
-	Address string
-	Ttl     int
+	Address  string
+	Ttl      int
+	Balancer string        // 负载均衡策略, 默认 bln_hash
+	WaitTime time.Duration // resolver 阻塞查询最长等待时间, 默认使用consul的5m
 }
 
 type RegisterConfig struct {
-	SvcName        string        // 服务名称
-	Address        string        // 服务ip
-	Port           int           // 服务端口
-	UpdateInterval time.Duration // 健康检查时间
-	Tag            string        // 服务版本号,非必填
+	SvcName        string            // 服务名称
+	Address        string            // 服务ip
+	Port           int               // 服务端口
+	UpdateInterval time.Duration     // 健康检查时间
+	Tag            string            // 服务版本号,非必填
+	Meta           map[string]string // 服务元数据, 例: {"zone": "a"}, 非必填
+
+	CheckType     string       // 健康检查类型 ttl/grpc/http, 默认ttl
+	CheckAddr     string       // grpc 默认为 Address:Port, http 为完整url
+	CheckInterval string       // grpc/http 检查间隔, 默认5s
+	HealthCheck   func() error // ttl 上报前调用, 返回错误则上报critical, 非必填
 }
@@ -4,16 +4,29 @@ import (
 	"fmt"
 	consulApi "github.com/hashicorp/consul/api"
 	"github.com/y1015860449/gotoolkit/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log"
+	"net"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// deregisterAfter 检查项 critical 持续该时间后 agent 注销服务
+const deregisterAfter = "5s"
+
 type Register struct {
-	client         *consulApi.Client
-	consulConf     *ConsulConfig
-	registerConf   *RegisterConfig
-	svcId          string
-	checkId        string
-	deregisterChan chan bool
+	client       *consulApi.Client
+	consulConf   *ConsulConfig
+	registerConf *RegisterConfig
+	svcId        string
+	checkId      string
+	status       string
+	output       string
+	mtx          sync.Mutex
+	stop         chan struct{} // 当前服务的 ttl 上报协程, 每个服务 id 只有一个
 }
 
 func NewRegister(consulConf *ConsulConfig) (*Register, error) {
@@ -24,19 +37,70 @@ func NewRegister(consulConf *ConsulConfig) (*Register, error) {
 		return nil, err
 	}
 	return &Register{
-		client:         cli,
-		consulConf:     consulConf,
-		deregisterChan: make(chan bool, 1),
+		client:     cli,
+		consulConf: consulConf,
+		status:     consulApi.HealthPassing,
 	}, nil
 }
 
+// RegisterHealthServer 在grpc服务上注册 grpc.health.v1, 供 CheckGRPC 使用
+func RegisterHealthServer(s *grpc.Server) *health.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, hs)
+	return hs
+}
+
+// ServiceRegister 注销前重复调用时沿用同一个服务 id 更新注册信息, 不会重复启动 ttl 上报
 func (register *Register) ServiceRegister(registerConf *RegisterConfig) error {
-	svcId := utils.GetUUID()
+	if register.consulConf.Ttl <= 0 {
+		register.consulConf.Ttl = 5
+	}
+	if len(registerConf.CheckType) <= 0 {
+		registerConf.CheckType = CheckTTL
+	}
+	register.mtx.Lock()
+	defer register.mtx.Unlock()
+	register.registerConf = registerConf
+	if len(register.svcId) <= 0 {
+		register.svcId = utils.GetUUID()
+		register.checkId = utils.GetUUID()
+	}
+	if err := register.register(); err != nil {
+		return err
+	}
+	if registerConf.CheckType != CheckTTL {
+		register.stopTTL()
+		return nil
+	}
+	if register.stop != nil {
+		return nil
+	}
+	interval := registerConf.UpdateInterval
+	if interval <= 0 {
+		interval = time.Duration(register.consulConf.Ttl) * time.Second / 2
+	}
+	register.stop = make(chan struct{})
+	go register.updateTTL(interval, register.stop)
+	return nil
+}
+
+// stopTTL 调用方持有锁
+func (register *Register) stopTTL() {
+	if register.stop != nil {
+		close(register.stop)
+		register.stop = nil
+	}
+}
+
+func (register *Register) register() error {
+	registerConf := register.registerConf
 	reg := &consulApi.AgentServiceRegistration{
-		ID:      svcId,
+		ID:      register.svcId,
 		Name:    registerConf.SvcName,
 		Port:    registerConf.Port,
 		Address: registerConf.Address,
+		Meta:    registerConf.Meta,
 	}
 	if len(registerConf.Tag) > 0 {
 		reg.Tags = []string{registerConf.Tag}
@@ -44,46 +108,117 @@ func (register *Register) ServiceRegister(registerConf *RegisterConfig) error {
 	if err := register.client.Agent().ServiceRegister(reg); err != nil {
 		return err
 	}
-	register.svcId = svcId
-	if register.consulConf.Ttl <= 0 {
-		register.consulConf.Ttl = 5
-	}
-	checkId := utils.GetUUID()
-	check := consulApi.AgentServiceCheck{
-		TTL:                            fmt.Sprintf("%ds", register.consulConf.Ttl),
-		Status:                         consulApi.HealthPassing,
-		Timeout:                        "1s",
-		DeregisterCriticalServiceAfter: "5s",
-	}
-	if err := register.client.Agent().CheckRegister(&consulApi.AgentCheckRegistration{
-		ID:                checkId,
+	return register.client.Agent().CheckRegister(&consulApi.AgentCheckRegistration{
+		ID:                register.checkId,
 		Name:              registerConf.SvcName,
-		ServiceID:         svcId,
-		AgentServiceCheck: check,
-	}); err != nil {
-		return err
+		ServiceID:         register.svcId,
+		AgentServiceCheck: register.check(),
+	})
+}
+
+func (register *Register) check() consulApi.AgentServiceCheck {
+	registerConf := register.registerConf
+	interval := registerConf.CheckInterval
+	if len(interval) <= 0 {
+		interval = "5s"
 	}
-	register.checkId = checkId
-	go func() {
-		ticker := time.NewTicker(registerConf.UpdateInterval)
-		for {
-			select {
-			case <-ticker.C:
-				_ = register.client.Agent().UpdateTTL(checkId, "", check.Status)
-			case <-register.deregisterChan:
-				break
+	switch registerConf.CheckType {
+	case CheckGRPC:
+		addr := registerConf.CheckAddr
+		if len(addr) <= 0 {
+			addr = net.JoinHostPort(registerConf.Address, strconv.Itoa(registerConf.Port))
+		}
+		return consulApi.AgentServiceCheck{
+			GRPC:                           addr,
+			Interval:                       interval,
+			Timeout:                        "1s",
+			DeregisterCriticalServiceAfter: deregisterAfter,
+		}
+	case CheckHTTP:
+		return consulApi.AgentServiceCheck{
+			HTTP:                           registerConf.CheckAddr,
+			Interval:                       interval,
+			Timeout:                        "1s",
+			DeregisterCriticalServiceAfter: deregisterAfter,
+		}
+	default:
+		return consulApi.AgentServiceCheck{
+			TTL:                            fmt.Sprintf("%ds", register.consulConf.Ttl),
+			Status:                         consulApi.HealthPassing,
+			DeregisterCriticalServiceAfter: deregisterAfter,
+		}
+	}
+}
+
+// updateTTL 定时上报状态, 检查项丢失(agent重启等)时重新注册
+func (register *Register) updateTTL(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := register.report(); err != nil {
+				log.Printf("[consul register] update ttl err(%+v)", err)
+				register.mtx.Lock()
+				select {
+				case <-stop:
+				default:
+					if err = register.register(); err != nil {
+						log.Printf("[consul register] re-register err(%+v)", err)
+					}
+				}
+				register.mtx.Unlock()
 			}
+		case <-stop:
+			return
 		}
-	}()
-	return nil
+	}
+}
+
+func (register *Register) report() error {
+	register.mtx.Lock()
+	status, output := register.status, register.output
+	registerConf, checkId := register.registerConf, register.checkId
+	register.mtx.Unlock()
+	if registerConf.HealthCheck != nil && status == consulApi.HealthPassing {
+		if err := registerConf.HealthCheck(); err != nil {
+			status, output = consulApi.HealthCritical, err.Error()
+		}
+	}
+	return register.client.Agent().UpdateTTL(checkId, output, status)
+}
+
+// SetStatus 设置ttl检查状态 passing/warning/critical, 立即上报
+func (register *Register) SetStatus(status, output string) error {
+	register.mtx.Lock()
+	register.status, register.output = status, output
+	ttl := register.registerConf != nil && register.registerConf.CheckType == CheckTTL && len(register.checkId) > 0
+	register.mtx.Unlock()
+	if !ttl {
+		return nil
+	}
+	return register.report()
+}
+
+// Drain 开启维护模式, 实例从健康列表中摘除但不注销
+func (register *Register) Drain() error {
+	register.mtx.Lock()
+	svcId := register.svcId
+	register.mtx.Unlock()
+	return register.client.Agent().EnableServiceMaintenance(svcId, "draining")
 }
 
+// ServiceDeregister 注销后再次 ServiceRegister 使用新的服务 id
 func (register *Register) ServiceDeregister() error {
-	register.deregisterChan <- true
-	if err := register.client.Agent().ServiceDeregister(register.svcId); err != nil {
+	register.mtx.Lock()
+	register.stopTTL()
+	svcId, checkId := register.svcId, register.checkId
+	register.svcId, register.checkId = "", ""
+	register.mtx.Unlock()
+	if err := register.client.Agent().ServiceDeregister(svcId); err != nil {
 		return err
 	}
-	if err := register.client.Agent().CheckDeregister(register.checkId); err != nil {
+	if err := register.client.Agent().CheckDeregister(checkId); err != nil {
 		return err
 	}
 	return nil
@@ -1,13 +1,16 @@
 package hxconsul
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	consulApi "github.com/hashicorp/consul/api"
 	"github.com/y1015860449/gotoolkit/discovery/balancer/hash"
+	"github.com/y1015860449/gotoolkit/discovery/balancer/zone"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/resolver"
+	"log"
 	"net"
 	"strconv"
 	"time"
@@ -21,6 +24,8 @@ type Resolver struct {
 	cc             resolver.ClientConn
 	grpcClientConn *grpc.ClientConn
 	lastIndex      uint64
+	ctx            context.Context
+	cancel         context.CancelFunc
 }
 
 func NewResolver(consulConf *ConsulConfig, svcName, tag string) (*Resolver, error) {
@@ -30,18 +35,26 @@ func NewResolver(consulConf *ConsulConfig, svcName, tag string) (*Resolver, erro
 	if err != nil {
 		return nil, err
 	}
+	ctx, cancel := context.WithCancel(context.Background())
 	r := &Resolver{
 		client:     cli,
 		consulConf: consulConf,
 		SvcName:    svcName,
+		Tag:        tag,
 		lastIndex:  0,
+		ctx:        ctx,
+		cancel:     cancel,
 	}
 	resolver.Register(r)
 
+	bln := consulConf.Balancer
+	if len(bln) <= 0 {
+		bln = hash.Name
+	}
 	conn, err := grpc.Dial(
 		fmt.Sprintf("%s:///%s", schemeName, svcName),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, hash.Name)),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, bln)),
 		grpc.WithInsecure(),
 		grpc.WithTimeout(time.Duration(5)*time.Second),
 	)
@@ -63,6 +76,7 @@ func (rlv *Resolver) ResolveNow(options resolver.ResolveNowOptions) {
 }
 
 func (rlv *Resolver) Close() {
+	rlv.cancel()
 }
 
 func (rlv *Resolver) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
@@ -75,38 +89,60 @@ func (rlv *Resolver) Build(target resolver.Target, cc resolver.ClientConn, opts
 		return nil, err
 	}
 	_ = rlv.cc.UpdateState(resolver.State{Addresses: addrList})
-	go rlv.watch(addrList)
+	go rlv.watch()
 	return rlv, nil
 }
 
+// serviceList lastIndex 不为0时为阻塞查询, 服务有变化或超时后返回
 func (rlv *Resolver) serviceList() ([]resolver.Address, error) {
-	resp, mate, err := rlv.client.Health().Service(rlv.SvcName, "", true, &consulApi.QueryOptions{
+	opts := &consulApi.QueryOptions{
 		WaitIndex: rlv.lastIndex,
-	})
+		WaitTime:  rlv.consulConf.WaitTime,
+	}
+	resp, mate, err := rlv.client.Health().Service(rlv.SvcName, rlv.Tag, true, opts.WithContext(rlv.ctx))
 	if err != nil {
 		return nil, err
 	}
 	var addrList []resolver.Address
 	for _, svc := range resp {
-		addrList = append(addrList, resolver.Address{Addr: net.JoinHostPort(svc.Service.Address, strconv.Itoa(svc.Service.Port))})
+		addr := resolver.Address{Addr: net.JoinHostPort(svc.Service.Address, strconv.Itoa(svc.Service.Port))}
+		addrList = append(addrList, zone.SetMetadata(addr, svc.Service.Meta))
+	}
+	// 索引回退(consul重启等)时重置, 否则阻塞查询会立即返回
+	if mate.LastIndex < rlv.lastIndex {
+		rlv.lastIndex = 0
+	} else {
+		rlv.lastIndex = mate.LastIndex
 	}
-	rlv.lastIndex = mate.LastIndex
 	return addrList, nil
 }
 
-func (rlv *Resolver) watch(addrList []resolver.Address) {
-	ticker := time.NewTicker(1 * time.Second)
-	var err error
+func (rlv *Resolver) watch() {
+	minDelay, maxDelay := 100*time.Millisecond, 30*time.Second
+	delay := minDelay
 	for {
-		select {
-		case <-ticker.C:
-			addrList, err = rlv.serviceList()
-			if err != nil {
-				continue
+		index := rlv.lastIndex
+		addrList, err := rlv.serviceList()
+		if rlv.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("[consul resolver] watch(%s) err(%+v)", rlv.SvcName, err)
+			select {
+			case <-rlv.ctx.Done():
+				return
+			case <-time.After(delay):
 			}
-			if err = rlv.cc.UpdateState(resolver.State{Addresses: addrList}); err != nil {
-				continue
+			if delay *= 2; delay > maxDelay {
+				delay = maxDelay
 			}
+			continue
+		}
+		delay = minDelay
+		if index == rlv.lastIndex {
+			// 等待超时, 没有变化
+			continue
 		}
+		_ = rlv.cc.UpdateState(resolver.State{Addresses: addrList})
 	}
 }
@@ -1,7 +1,10 @@
 package hxetcd
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/y1015860449/gotoolkit/discovery/balancer/zone"
+	"google.golang.org/grpc/resolver"
 	"time"
 )
 
@@ -10,12 +13,40 @@ const schemeName = "etcd"
 type EtcdConfig struct {
 	Endpoints   []string      `json:"endpoints"`
 	DialTimeout time.Duration `json:"dialTimeout"`
+	Balancer    string        `json:"balancer"` // 负载均衡策略, 默认 round_robin
 }
 
 type ServiceInfo struct {
-	svcName string
-	SvcIp   string
-	SvcPort int
+	SvcName  string
+	SvcIp    string
+	SvcPort  int
+	Metadata map[string]string // 实例元数据, 例: {"zone": "a"}, 非必填
+}
+
+// instance 带元数据时写入etcd的value
+type instance struct {
+	Addr     string            `json:"addr"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// encodeInstance 无元数据时仍只写地址, 兼容旧版本
+func encodeInstance(addr string, md map[string]string) string {
+	if len(md) == 0 {
+		return addr
+	}
+	data, err := json.Marshal(&instance{Addr: addr, Metadata: md})
+	if err != nil {
+		return addr
+	}
+	return string(data)
+}
+
+func decodeInstance(value []byte) resolver.Address {
+	var ins instance
+	if err := json.Unmarshal(value, &ins); err != nil || len(ins.Addr) <= 0 {
+		return resolver.Address{Addr: string(value)}
+	}
+	return zone.SetMetadata(resolver.Address{Addr: ins.Addr}, ins.Metadata)
 }
 
 func GetPrefix(schema, serviceName string) string {
@@ -5,6 +5,7 @@ import (
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"net"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -15,6 +16,9 @@ type Register struct {
 	etcdCli     *clientv3.Client
 	leaseId     clientv3.LeaseID
 	keepAliveCh <-chan *clientv3.LeaseKeepAliveResponse
+	cancel      context.CancelFunc
+	stopCh      chan struct{}
+	mtx         sync.Mutex
 }
 
 func NewRegister(etcdConfig *EtcdConfig) (*Register, error) {
@@ -29,16 +33,27 @@ func NewRegister(etcdConfig *EtcdConfig) (*Register, error) {
 }
 
 func (r *Register) ServiceRegister(svcInfo *ServiceInfo, ttl int64) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
 	r.svcInfo = svcInfo
 	r.svcTTL = ttl
-	err := r.register()
-	if err != nil {
+	if err := r.register(); err != nil {
 		return err
 	}
-	go r.keepAlive()
+	if r.stopCh == nil {
+		r.stopCh = make(chan struct{})
+		go r.keepAlive(r.stopCh)
+	}
 	return nil
 }
 
+func (r *Register) serviceKey() (string, string) {
+	serviceAddr := net.JoinHostPort(r.svcInfo.SvcIp, strconv.Itoa(r.svcInfo.SvcPort))
+	serviceKey := GetPrefix(schemeName, r.svcInfo.SvcName) + "/" + serviceAddr
+	return serviceKey, encodeInstance(serviceAddr, r.svcInfo.Metadata)
+}
+
+// register 申请新租约写入实例, 成功后再释放旧租约
 func (r *Register) register() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -46,53 +61,90 @@ func (r *Register) register() error {
 	if err != nil {
 		return err
 	}
-	r.keepAliveCh, err = r.etcdCli.KeepAlive(context.Background(), resp.ID)
-	if err != nil {
+	serviceKey, serviceValue := r.serviceKey()
+	if _, err = r.etcdCli.Put(ctx, serviceKey, serviceValue, clientv3.WithLease(resp.ID)); err != nil {
+		_, _ = r.etcdCli.Revoke(ctx, resp.ID)
 		return err
 	}
-	serviceValue := net.JoinHostPort(r.svcInfo.SvcIp, strconv.Itoa(r.svcInfo.SvcPort))
-	serviceKey := GetPrefix(schemeName, r.svcInfo.svcName) + "/" + serviceValue
-	if _, err = r.etcdCli.Put(ctx, serviceKey, serviceValue, clientv3.WithLease(resp.ID)); err != nil {
+	kaCtx, kaCancel := context.WithCancel(context.Background())
+	keepAliveCh, err := r.etcdCli.KeepAlive(kaCtx, resp.ID)
+	if err != nil {
+		kaCancel()
+		_, _ = r.etcdCli.Revoke(ctx, resp.ID)
 		return err
 	}
+	if r.cancel != nil {
+		r.cancel()
+	}
 	if r.leaseId > 0 {
 		_, _ = r.etcdCli.Revoke(ctx, r.leaseId)
 	}
 	r.leaseId = resp.ID
+	r.keepAliveCh = keepAliveCh
+	r.cancel = kaCancel
 	return nil
 }
 
-func (r *Register) keepAlive() {
-	t := time.NewTicker(time.Duration(r.svcTTL/2) * time.Second)
+// keepAlive 租约丢失(过期或连接断开)后自动重新注册
+func (r *Register) keepAlive(stopCh chan struct{}) {
+	interval := time.Duration(r.svcTTL/2) * time.Second
+	if interval <= 0 {
+		interval = time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
 	for {
+		r.mtx.Lock()
+		keepAliveCh := r.keepAliveCh
+		r.mtx.Unlock()
 		select {
-		case _, ok := <-r.keepAliveCh:
-			if !ok {
-				if err := r.register(); err != nil {
-					continue
-				}
+		case <-stopCh:
+			return
+		case _, ok := <-keepAliveCh:
+			if ok {
+				continue
 			}
+			r.mtx.Lock()
+			// 已被新租约替换的通道关闭时忽略
+			if r.keepAliveCh == keepAliveCh {
+				r.keepAliveCh = nil
+				_ = r.register()
+			}
+			r.mtx.Unlock()
 		case <-t.C:
+			r.mtx.Lock()
 			if r.keepAliveCh == nil {
-				if err := r.register(); err != nil {
-					continue
-				}
+				_ = r.register()
 			}
+			r.mtx.Unlock()
 		}
 	}
-
 }
 
 func (r *Register) ServiceDeregister() error {
-	serviceValue := net.JoinHostPort(r.svcInfo.SvcIp, strconv.Itoa(r.svcInfo.SvcPort))
-	serviceKey := GetPrefix(schemeName, r.svcInfo.svcName) + "/" + serviceValue
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.stopCh != nil {
+		close(r.stopCh)
+		r.stopCh = nil
+	}
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	if r.leaseId <= 0 {
+		return nil
+	}
+	serviceKey, _ := r.serviceKey()
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	if _, err := r.etcdCli.Delete(ctx, serviceKey, clientv3.WithLease(r.leaseId)); err != nil {
+	if _, err := r.etcdCli.Delete(ctx, serviceKey); err != nil {
 		return err
 	}
-	if _, err := r.etcdCli.Revoke(context.Background(), r.leaseId); err != nil {
+	if _, err := r.etcdCli.Revoke(ctx, r.leaseId); err != nil {
 		return err
 	}
+	r.leaseId = 0
+	r.keepAliveCh = nil
 	return nil
 }
@@ -35,7 +35,7 @@ func TestRegister_RegisterEtcd(t *testing.T) {
 	}
 
 	if err = reg.ServiceRegister(&ServiceInfo{
-		svcName: "etcd_hello",
+		SvcName: "etcd_hello",
 		SvcIp:   "192.168.166.125",
 		SvcPort: 8868,
 	}, 5); err != nil {
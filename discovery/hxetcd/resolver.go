@@ -43,10 +43,14 @@ func NewResolver(etcdConfig *EtcdConfig, svcName string) (*Resolver, error) {
 	r.cli = etcdCli
 	resolver.Register(&r)
 
+	bln := etcdConfig.Balancer
+	if len(bln) <= 0 {
+		bln = roundrobin.Name
+	}
 	conn, err := grpc.Dial(
 		GetPrefix(schemeName, svcName),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, roundrobin.Name)),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, bln)),
 		grpc.WithInsecure(),
 		grpc.WithTimeout(time.Duration(5)*time.Second),
 	)
@@ -94,7 +98,8 @@ func (r *Resolver) Build(target resolver.Target, cc resolver.ClientConn, opts re
 	}
 	r.cc = cc
 
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 	//     "%s:///%s"
 	prefix := GetPrefix(schemeName, r.svcName)
 	// get key first
@@ -102,7 +107,7 @@ func (r *Resolver) Build(target resolver.Target, cc resolver.ClientConn, opts re
 	if err == nil {
 		var addrList []resolver.Address
 		for i := range resp.Kvs {
-			addrList = append(addrList, resolver.Address{Addr: string(resp.Kvs[i].Value)})
+			addrList = append(addrList, decodeInstance(resp.Kvs[i].Value))
 		}
 		r.cc.UpdateState(resolver.State{Addresses: addrList})
 		r.watchStartRevision = resp.Header.Revision + 1
@@ -118,9 +123,9 @@ func (r *Resolver) Scheme() string {
 	return schemeName
 }
 
-func exists(addrList []resolver.Address, addr string) bool {
+func exists(addrList []resolver.Address, addr resolver.Address) bool {
 	for _, v := range addrList {
-		if v.Addr == addr {
+		if v.Equal(addr) {
 			return true
 		}
 	}
@@ -144,9 +149,13 @@ func (r *Resolver) watch(prefix string, addrList []resolver.Address) {
 		for _, ev := range n.Events {
 			switch ev.Type {
 			case clientv3.EventTypePut:
-				if !exists(addrList, string(ev.Kv.Value)) {
+				addr := decodeInstance(ev.Kv.Value)
+				if !exists(addrList, addr) {
 					flag = 1
-					addrList = append(addrList, resolver.Address{Addr: string(ev.Kv.Value)})
+					if s, ok := remove(addrList, addr.Addr); ok {
+						addrList = s
+					}
+					addrList = append(addrList, addr)
 				}
 			case clientv3.EventTypeDelete:
 				i := strings.LastIndexAny(string(ev.Kv.Key), "/")
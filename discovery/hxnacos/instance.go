@@ -21,6 +21,7 @@ type NacosConfig struct {
 	CacheDir            string // 缓存地址
 	LogDir              string // 日志地址
 	LogLevel            string // 日志等级
+	Balancer            string // 负载均衡策略, 默认 bln_hash
 }
 
 func DefaultNacosConfig() *NacosConfig {
@@ -47,11 +47,36 @@ func (register *Register) ServiceRegister(config *RegisterConfig) error {
 		GroupName:   config.GroupName,
 		Ephemeral:   config.Ephemeral,
 	}
-	_, err := register.namingClient.RegisterInstance(param)
+	if _, err := register.namingClient.RegisterInstance(param); err != nil {
+		return err
+	}
+	register.registerConfig = config
+	return nil
+}
+
+// Drain 将实例置为不可用, 订阅方不再选择该实例
+func (register *Register) Drain() error {
+	if register.registerConfig == nil {
+		return nil
+	}
+	config := register.registerConfig
+	_, err := register.namingClient.UpdateInstance(vo.UpdateInstanceParam{
+		Ip:          config.SvcIp,
+		Port:        config.SvcPort,
+		Weight:      10,
+		Enable:      false,
+		Metadata:    config.Metadata,
+		ServiceName: config.SvcName,
+		GroupName:   config.GroupName,
+		Ephemeral:   config.Ephemeral,
+	})
 	return err
 }
 
 func (register *Register) ServiceDeregister() error {
+	if register.registerConfig == nil {
+		return nil
+	}
 	_, err := register.namingClient.DeregisterInstance(vo.DeregisterInstanceParam{
 		Ip:          register.registerConfig.SvcIp,
 		Port:        register.registerConfig.SvcPort,
@@ -8,10 +8,11 @@ import (
 	"github.com/nacos-group/nacos-sdk-go/v2/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/v2/model"
 	"github.com/nacos-group/nacos-sdk-go/v2/vo"
+	"github.com/y1015860449/gotoolkit/discovery/balancer/hash"
+	"github.com/y1015860449/gotoolkit/discovery/balancer/zone"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/resolver"
-	"gotoolkit/discovery/balancer/hash"
 	"log"
 	"net"
 	"sort"
@@ -49,10 +50,14 @@ func NewResolver(config *NacosConfig, svcName, groupName string) (*Resolver, err
 	}
 	resolver.Register(r)
 
+	bln := config.Balancer
+	if len(bln) <= 0 {
+		bln = hash.Name
+	}
 	conn, err := grpc.Dial(
 		fmt.Sprintf("%s:///%s", schemeName, svcName),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, hash.Name)),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, bln)),
 		grpc.WithInsecure(),
 		grpc.WithTimeout(time.Duration(5)*time.Second),
 	)
@@ -110,7 +115,8 @@ func (rlv *Resolver) serviceList() ([]resolver.Address, error) {
 	var addrList []resolver.Address
 	for _, instance := range instances {
 		if instance.Healthy && instance.Enable {
-			addrList = append(addrList, resolver.Address{Addr: net.JoinHostPort(instance.Ip, fmt.Sprintf("%d", instance.Port))})
+			addr := resolver.Address{Addr: net.JoinHostPort(instance.Ip, fmt.Sprintf("%d", instance.Port))}
+			addrList = append(addrList, zone.SetMetadata(addr, instance.Metadata))
 		}
 	}
 	if len(addrList) > 0 {
@@ -1,18 +1,60 @@
 package hxzookeeper
 
 import (
+	"encoding/json"
+	"github.com/y1015860449/gotoolkit/discovery/balancer/zone"
+	"google.golang.org/grpc/resolver"
+	"strings"
 	"time"
 )
 
-const schemeName = "zookeeper"
+const (
+	schemeName      = "zookeeper"
+	protectedPrefix = "_c_" // 与 zk 库保护节点前缀一致
+	// instancePrefix 实例节点名前缀, 实际节点名为 _c_<guid>-instance-<zk顺序号>, 地址只保存在节点数据中.
+	// 旧版本注册的节点名为 ip:port, 新版 resolver 仍可解析; 但旧版 resolver 会把节点名当作地址,
+	// 无法解析新节点, 滚动升级时需先升级所有调用方的 resolver, 再升级服务端
+	instancePrefix = "instance-"
+)
 
 type ZkConfig struct {
-	Urls    []string
-	Timeout time.Duration
+	Urls     []string
+	Timeout  time.Duration
+	Balancer string // 负载均衡策略, 默认 bln_hash
 }
 
 type ServiceInfo struct {
-	svcName string
-	SvcIp   string
-	SvcPort int
+	SvcName  string
+	SvcIp    string
+	SvcPort  int
+	Metadata map[string]string // 实例元数据, 例: {"zone": "a"}, 非必填
+}
+
+// instance 实例节点中保存的数据
+type instance struct {
+	Addr     string            `json:"addr"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func encodeInstance(addr string, md map[string]string) []byte {
+	data, _ := json.Marshal(&instance{Addr: addr, Metadata: md})
+	return data
+}
+
+// decodeInstance 节点数据为空时以节点名作为地址, 兼容旧版本
+func decodeInstance(node string, data []byte) resolver.Address {
+	var ins instance
+	if err := json.Unmarshal(data, &ins); err != nil || len(ins.Addr) <= 0 {
+		return resolver.Address{Addr: trimProtected(node)}
+	}
+	return zone.SetMetadata(resolver.Address{Addr: ins.Addr}, ins.Metadata)
+}
+
+// trimProtected 去掉 CreateProtectedEphemeralSequential 添加的 "_c_<guid>-" 前缀
+func trimProtected(node string) string {
+	const n = len(protectedPrefix) + 32 + 1
+	if strings.HasPrefix(node, protectedPrefix) && len(node) > n && node[n-1] == '-' {
+		return node[n:]
+	}
+	return node
 }
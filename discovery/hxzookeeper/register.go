@@ -3,63 +3,137 @@ package hxzookeeper
 import (
 	"fmt"
 	"github.com/go-zookeeper/zk"
+	"log"
+	"sync"
 )
 
+// Register 以临时顺序节点注册实例, 与旧版 resolver 不兼容, 见 instancePrefix
 type Register struct {
-	conn    *zk.Conn
-	conf    *ZkConfig
-	svcInfo *ServiceInfo
+	conn        *zk.Conn
+	conf        *ZkConfig
+	svcInfo     *ServiceInfo
+	path        string // 实际创建的临时顺序节点
+	sessionLost chan struct{}
+	mtx         sync.Mutex
 }
 
 func NewRegister(zkConfig *ZkConfig) (*Register, error) {
-	conn, _, err := zk.Connect(zkConfig.Urls, zkConfig.Timeout)
+	conn, events, err := zk.Connect(zkConfig.Urls, zkConfig.Timeout)
 	if err != nil {
 		return nil, err
 	}
-	return &Register{
-		conn: conn,
-		conf: zkConfig,
-	}, nil
+	r := &Register{
+		conn:        conn,
+		conf:        zkConfig,
+		sessionLost: make(chan struct{}, 1),
+	}
+	go r.watchSession(events)
+	return r, nil
+}
+
+// watchSession 会话过期后临时节点被删除, 重新建立会话时重建节点并通知
+func (r *Register) watchSession(events <-chan zk.Event) {
+	expired := false
+	for e := range events {
+		switch e.State {
+		case zk.StateExpired:
+			expired = true
+		case zk.StateHasSession:
+			if !expired {
+				continue
+			}
+			expired = false
+			if err := r.recover(); err != nil {
+				log.Printf("[zookeeper register] recover node err(%+v)", err)
+			}
+			select {
+			case r.sessionLost <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// SessionLost 会话过期并重连后触发, 节点已自动重建, 重复调用 ServiceRegister 无副作用
+func (r *Register) SessionLost() <-chan struct{} {
+	return r.sessionLost
+}
+
+func (r *Register) recover() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.svcInfo == nil {
+		return nil
+	}
+	return r.register(r.svcInfo)
 }
 
 func (r *Register) ServiceRegister(svcInfo *ServiceInfo) error {
-	existsOrCreate := func(path string, flag int32) (bool, error) {
-		exist, _, err := r.conn.Exists(path)
-		if err != nil {
-			return false, err
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if err := r.register(svcInfo); err != nil {
+		return err
+	}
+	r.svcInfo = svcInfo
+	return nil
+}
+
+// register 节点仍存在则更新数据, 否则创建新的临时顺序保护节点
+func (r *Register) register(svcInfo *ServiceInfo) error {
+	addr := fmt.Sprintf("%s:%d", svcInfo.SvcIp, svcInfo.SvcPort)
+	data := encodeInstance(addr, svcInfo.Metadata)
+	if len(r.path) > 0 {
+		_, stat, err := r.conn.Get(r.path)
+		if err == nil {
+			_, err = r.conn.Set(r.path, data, stat.Version)
+			return err
 		}
-		if !exist {
-			_, err = r.conn.Create(path, nil, flag, zk.WorldACL(zk.PermAll))
-			if err != nil {
-				return false, err
-			}
+		if err != zk.ErrNoNode {
+			return err
 		}
-		return exist, nil
 	}
 
-	node := fmt.Sprintf("/%s/%s", schemeName, svcInfo.svcName)
-	if _, err := existsOrCreate(node, 0); err != nil {
+	node := fmt.Sprintf("/%s/%s", schemeName, svcInfo.SvcName)
+	if err := r.createParents(node); err != nil {
 		return err
 	}
-	path := fmt.Sprintf("/%s/%s/%s:%d", schemeName, svcInfo.svcName, svcInfo.SvcIp, svcInfo.SvcPort)
-	exist, err := existsOrCreate(path, int32(zk.FlagEphemeral))
+	// 保护节点: 创建时连接断开, 重试可找回已创建的节点, 不会遗留重复实例
+	path, err := r.conn.CreateProtectedEphemeralSequential(node+"/"+instancePrefix, data, zk.WorldACL(zk.PermAll))
 	if err != nil {
 		return err
 	}
-	if exist {
-		// 存在则更新
-		if _, stat, err := r.conn.Get(path); err == nil {
-			_, err = r.conn.Set(path, nil, stat.Version)
+	r.path = path
+	return nil
+}
+
+func (r *Register) createParents(node string) error {
+	for _, path := range []string{"/" + schemeName, node} {
+		exist, _, err := r.conn.Exists(path)
+		if err != nil {
+			return err
+		}
+		if exist {
+			continue
+		}
+		if _, err = r.conn.Create(path, nil, 0, zk.WorldACL(zk.PermAll)); err != nil && err != zk.ErrNodeExists {
+			return err
 		}
 	}
 	return nil
 }
 
 func (r *Register) ServiceDeregister() error {
-	path := fmt.Sprintf("%s/%s/%s:%d", schemeName, r.svcInfo.svcName, r.svcInfo.SvcIp, r.svcInfo.SvcPort)
-	_, stat, err := r.conn.Get(path)
-	if err != nil {
-		return err
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.path) <= 0 {
+		return nil
+	}
+	path := r.path
+	r.svcInfo = nil
+	r.path = ""
+	err := r.conn.Delete(path, -1)
+	if err == zk.ErrNoNode {
+		return nil
 	}
-	return r.conn.Delete(path, stat.Version)
+	return err
 }
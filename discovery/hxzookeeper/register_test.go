@@ -33,7 +33,7 @@ func TestRegister_RegisterNacos(t *testing.T) {
 	}
 
 	rc := &ServiceInfo{
-		svcName: "zk_hello",
+		SvcName: "zk_hello",
 		SvcIp:   "192.168.166.125",
 		SvcPort: 8868,
 	}
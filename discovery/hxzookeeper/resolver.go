@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-zookeeper/zk"
+	"github.com/y1015860449/gotoolkit/discovery/balancer/hash"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/resolver"
-	"gotoolkit/discovery/balancer/hash"
+	"log"
+	"sync"
 	"time"
 )
 
@@ -17,24 +19,34 @@ type Resolver struct {
 	svcName        string
 	cc             resolver.ClientConn
 	grpcClientConn *grpc.ClientConn
+	refreshCh      chan struct{}
+	closeCh        chan struct{}
+	closeOnce      sync.Once
 }
 
 func NewResolver(zkConfig *ZkConfig, svcName string) (*Resolver, error) {
-	conn, _, err := zk.Connect(zkConfig.Urls, zkConfig.Timeout)
+	conn, events, err := zk.Connect(zkConfig.Urls, zkConfig.Timeout)
 	if err != nil {
 		return nil, err
 	}
 	r := &Resolver{
-		conn:    conn,
-		conf:    zkConfig,
-		svcName: svcName,
+		conn:      conn,
+		conf:      zkConfig,
+		svcName:   svcName,
+		refreshCh: make(chan struct{}, 1),
+		closeCh:   make(chan struct{}),
 	}
+	go r.watchSession(events)
 	resolver.Register(r)
+	bln := zkConfig.Balancer
+	if len(bln) <= 0 {
+		bln = hash.Name
+	}
 	grpcConn, err := grpc.Dial(
 		fmt.Sprintf("%s:///%s", schemeName, svcName),
 		grpc.WithResolvers(r),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, hash.Name)),
+		grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"LoadBalancingPolicy": "%s"}`, bln)),
 		grpc.WithTimeout(time.Duration(5)*time.Second),
 	)
 	if err == nil {
@@ -43,11 +55,39 @@ func NewResolver(zkConfig *ZkConfig, svcName string) (*Resolver, error) {
 	return r, nil
 }
 
+// watchSession 会话过期重连后重建 watch 和地址列表
+func (rlv *Resolver) watchSession(events <-chan zk.Event) {
+	expired := false
+	for e := range events {
+		switch e.State {
+		case zk.StateExpired:
+			expired = true
+		case zk.StateHasSession:
+			if expired {
+				expired = false
+				rlv.refresh()
+			}
+		}
+	}
+}
+
+func (rlv *Resolver) refresh() {
+	select {
+	case rlv.refreshCh <- struct{}{}:
+	default:
+	}
+}
+
 func (rlv *Resolver) ResolveNow(rn resolver.ResolveNowOptions) {
 	_ = rn
+	rlv.refresh()
 }
 
 func (rlv *Resolver) Close() {
+	rlv.closeOnce.Do(func() {
+		close(rlv.closeCh)
+		rlv.conn.Close()
+	})
 }
 
 func (rlv *Resolver) GetConn() *grpc.ClientConn {
@@ -62,18 +102,7 @@ func (rlv *Resolver) Build(target resolver.Target, cc resolver.ClientConn, opts
 	}
 	rlv.cc = cc
 	prefix := fmt.Sprintf("/%s/%s", schemeName, rlv.svcName)
-	nodes, _, err := rlv.conn.Children(prefix)
-	if err == nil {
-		var addrList []resolver.Address
-		for _, node := range nodes {
-			addrList = append(addrList, resolver.Address{Addr: node})
-		}
-		_ = rlv.cc.UpdateState(resolver.State{Addresses: addrList})
-		go rlv.watch(prefix, addrList)
-	} else {
-		return nil, err
-	}
-
+	go rlv.watch(prefix)
 	return rlv, nil
 }
 
@@ -81,64 +110,46 @@ func (rlv *Resolver) Scheme() string {
 	return schemeName
 }
 
-func exists(addrList []resolver.Address, addr string) bool {
-	for _, v := range addrList {
-		if v.Addr == addr {
-			return true
-		}
-	}
-	return false
-}
-
-func remove(s []resolver.Address, addr string) ([]resolver.Address, bool) {
-	for i := range s {
-		if s[i].Addr == addr {
-			s[i] = s[len(s)-1]
-			return s[:len(s)-1], true
+func (rlv *Resolver) serviceList(prefix string, nodes []string) []resolver.Address {
+	var addrList []resolver.Address
+	for _, node := range nodes {
+		data, _, err := rlv.conn.Get(prefix + "/" + node)
+		if err != nil {
+			continue
 		}
+		addrList = append(addrList, decodeInstance(node, data))
 	}
-	return nil, false
+	return addrList
 }
 
-func (rlv *Resolver) watch(prefix string, addrList []resolver.Address) {
+// watch 每次触发后重新获取子节点并重新注册 watch, 出错时等待重试
+func (rlv *Resolver) watch(prefix string) {
+	retry := time.Second
 	for {
-		flag := 0
-		snapshot, _, ch, err := rlv.conn.ChildrenW(prefix)
+		nodes, _, ch, err := rlv.conn.ChildrenW(prefix)
 		if err != nil {
-			return
+			log.Printf("[zookeeper resolver] watch(%s) err(%+v)", prefix, err)
+			if err == zk.ErrNoNode {
+				_ = rlv.cc.UpdateState(resolver.State{})
+			}
+			select {
+			case <-rlv.closeCh:
+				return
+			case <-rlv.refreshCh:
+			case <-time.After(retry):
+			}
+			continue
 		}
+		_ = rlv.cc.UpdateState(resolver.State{Addresses: rlv.serviceList(prefix, nodes)})
 		select {
+		case <-rlv.closeCh:
+			return
+		case <-rlv.refreshCh:
 		case e := <-ch:
-			switch e.Type {
-			case zk.EventNodeDeleted:
-				for _, node := range snapshot {
-					if s, ok := remove(addrList, node); ok {
-						flag = 1
-						addrList = s
-					}
-				}
-			case zk.EventNodeCreated:
-				for _, node := range snapshot {
-					if !exists(addrList, node) {
-						flag = 1
-						addrList = append(addrList, resolver.Address{Addr: node})
-					}
-				}
-			case zk.EventNodeChildrenChanged:
-				snapshot, _, err = rlv.conn.Children(prefix)
-				if err == nil {
-					flag = 1
-					addrList = addrList[0:0]
-					for _, node := range snapshot {
-						addrList = append(addrList, resolver.Address{Addr: node})
-					}
-				}
+			// 会话过期时 watch 失效, 收到 EventNotWatching, 重新注册即可
+			if e.Type == zk.EventNotWatching {
+				log.Printf("[zookeeper resolver] watch(%s) lost err(%+v)", prefix, e.Err)
 			}
-
-		}
-		// 更新
-		if flag == 1 {
-			_ = rlv.cc.UpdateState(resolver.State{Addresses: addrList})
 		}
 	}
 }
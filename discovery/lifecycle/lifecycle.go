@@ -0,0 +1,256 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Registry 服务注销, discovery 下各 Register 均已实现
+type Registry interface {
+	ServiceDeregister() error
+}
+
+// Drainer 可选, 注销前先将实例摘流(标记为不可用)
+type Drainer interface {
+	Drain() error
+}
+
+// SessionNotifier 可选, 注册中心会话丢失后通知重新注册
+type SessionNotifier interface {
+	SessionLost() <-chan struct{}
+}
+
+type Config struct {
+	Readiness       func(ctx context.Context) error // 就绪探针, 通过后才注册, 非必填
+	ReadyInterval   time.Duration                   // 就绪探测间隔, 默认1s
+	DrainDelay      time.Duration                   // 摘流后等待调用方刷新地址的时间, 默认3s
+	ShutdownTimeout time.Duration                   // 等待在途请求完成的最长时间, 默认30s
+	Signals         []os.Signal                     // 触发优雅退出的信号, 默认 SIGINT SIGTERM
+	GrpcServer      *grpc.Server                    // 非必填, 退出时 GracefulStop
+	HealthServer    *health.Server                  // 非必填, 同步 grpc.health.v1 状态
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		ReadyInterval:   time.Second,
+		DrainDelay:      3 * time.Second,
+		ShutdownTimeout: 30 * time.Second,
+		Signals:         []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+	}
+}
+
+type Lifecycle struct {
+	conf     *Config
+	registry Registry
+	register func() error
+	inflight int64
+	draining int32
+	stopCh   chan struct{}
+	once     sync.Once
+	err      error
+}
+
+// New register 为实际的注册调用, 例: func() error { return reg.ServiceRegister(info, 5) }
+func New(registry Registry, register func() error, conf *Config) *Lifecycle {
+	def := DefaultConfig()
+	if conf == nil {
+		conf = def
+	}
+	if conf.ReadyInterval <= 0 {
+		conf.ReadyInterval = def.ReadyInterval
+	}
+	if conf.DrainDelay < 0 {
+		conf.DrainDelay = 0
+	}
+	if conf.ShutdownTimeout <= 0 {
+		conf.ShutdownTimeout = def.ShutdownTimeout
+	}
+	if len(conf.Signals) <= 0 {
+		conf.Signals = def.Signals
+	}
+	return &Lifecycle{
+		conf:     conf,
+		registry: registry,
+		register: register,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 等待就绪探针通过后注册服务
+func (l *Lifecycle) Start(ctx context.Context) error {
+	if l.register == nil || l.registry == nil {
+		return errors.New("params is exception")
+	}
+	if err := l.waitReady(ctx); err != nil {
+		return err
+	}
+	if err := l.register(); err != nil {
+		return err
+	}
+	l.setServing(healthpb.HealthCheckResponse_SERVING)
+	if n, ok := l.registry.(SessionNotifier); ok {
+		go l.watchSession(n.SessionLost())
+	}
+	return nil
+}
+
+func (l *Lifecycle) waitReady(ctx context.Context) error {
+	if l.conf.Readiness == nil {
+		return nil
+	}
+	ticker := time.NewTicker(l.conf.ReadyInterval)
+	defer ticker.Stop()
+	for {
+		err := l.conf.Readiness(ctx)
+		if err == nil {
+			return nil
+		}
+		log.Printf("lifecycle readiness err(%+v)", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchSession 会话丢失后重新注册, 失败按指数退避重试
+func (l *Lifecycle) watchSession(lost <-chan struct{}) {
+	minDelay, maxDelay := 100*time.Millisecond, 30*time.Second
+	for {
+		select {
+		case <-l.stopCh:
+			return
+		case <-lost:
+		}
+		delay := minDelay
+		for !l.Draining() {
+			err := l.register()
+			if err == nil {
+				log.Printf("lifecycle re-register success")
+				break
+			}
+			log.Printf("lifecycle re-register err(%+v)", err)
+			select {
+			case <-l.stopCh:
+				return
+			case <-time.After(delay):
+			}
+			if delay *= 2; delay > maxDelay {
+				delay = maxDelay
+			}
+		}
+	}
+}
+
+// Draining 是否处于摘流退出中
+func (l *Lifecycle) Draining() bool {
+	return atomic.LoadInt32(&l.draining) == 1
+}
+
+// Inflight 当前在途请求数, 需要使用拦截器统计
+func (l *Lifecycle) Inflight() int64 {
+	return atomic.LoadInt64(&l.inflight)
+}
+
+// Wait 阻塞到收到退出信号, 然后优雅退出
+func (l *Lifecycle) Wait() error {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, l.conf.Signals...)
+	defer signal.Stop(ch)
+	select {
+	case sig := <-ch:
+		log.Printf("lifecycle receive signal(%v)", sig)
+	case <-l.stopCh:
+	}
+	return l.Shutdown()
+}
+
+// Shutdown 摘流 -> 等待调用方刷新 -> 等待在途请求 -> 停止服务 -> 注销, 可重复调用
+func (l *Lifecycle) Shutdown() error {
+	l.once.Do(func() {
+		atomic.StoreInt32(&l.draining, 1)
+		close(l.stopCh)
+		l.setServing(healthpb.HealthCheckResponse_NOT_SERVING)
+
+		drainer, drain := l.registry.(Drainer)
+		if drain {
+			if err := drainer.Drain(); err != nil {
+				log.Printf("lifecycle drain err(%+v)", err)
+				drain = false
+			}
+		}
+		if !drain {
+			l.err = l.registry.ServiceDeregister()
+		}
+		time.Sleep(l.conf.DrainDelay)
+
+		deadline := time.Now().Add(l.conf.ShutdownTimeout)
+		l.waitInflight(deadline)
+		l.stopServer(deadline)
+
+		if drain {
+			l.err = l.registry.ServiceDeregister()
+		}
+	})
+	return l.err
+}
+
+func (l *Lifecycle) waitInflight(deadline time.Time) {
+	for l.Inflight() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if n := l.Inflight(); n > 0 {
+		log.Printf("lifecycle shutdown timeout, inflight(%d)", n)
+	}
+}
+
+func (l *Lifecycle) stopServer(deadline time.Time) {
+	if l.conf.GrpcServer == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		l.conf.GrpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Until(deadline)):
+		l.conf.GrpcServer.Stop()
+	}
+}
+
+func (l *Lifecycle) setServing(status healthpb.HealthCheckResponse_ServingStatus) {
+	if l.conf.HealthServer != nil {
+		l.conf.HealthServer.SetServingStatus("", status)
+	}
+}
+
+// UnaryServerInterceptor 统计在途请求
+func (l *Lifecycle) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt64(&l.inflight, 1)
+		defer atomic.AddInt64(&l.inflight, -1)
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor 统计在途流
+func (l *Lifecycle) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		atomic.AddInt64(&l.inflight, 1)
+		defer atomic.AddInt64(&l.inflight, -1)
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,92 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeRegistry struct {
+	mu          sync.Mutex
+	calls       []string
+	sessionLost chan struct{}
+}
+
+func (f *fakeRegistry) add(call string) {
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	f.mu.Unlock()
+}
+
+func (f *fakeRegistry) get() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func (f *fakeRegistry) ServiceDeregister() error {
+	f.add("deregister")
+	return nil
+}
+
+func (f *fakeRegistry) Drain() error {
+	f.add("drain")
+	return nil
+}
+
+func (f *fakeRegistry) SessionLost() <-chan struct{} {
+	return f.sessionLost
+}
+
+func TestLifecycle(t *testing.T) {
+	reg := &fakeRegistry{sessionLost: make(chan struct{}, 1)}
+	probes := 0
+	conf := DefaultConfig()
+	conf.ReadyInterval = 10 * time.Millisecond
+	conf.DrainDelay = 0
+	conf.Readiness = func(ctx context.Context) error {
+		if probes++; probes < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	}
+	l := New(reg, func() error {
+		reg.add("register")
+		return nil
+	}, conf)
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatalf("start err(%v)", err)
+	}
+	if probes != 3 {
+		t.Errorf("want 3 probes, got %d", probes)
+	}
+
+	reg.sessionLost <- struct{}{}
+	time.Sleep(50 * time.Millisecond)
+
+	handler := l.UnaryServerInterceptor()
+	go handler(context.Background(), nil, nil, func(ctx context.Context, req interface{}) (interface{}, error) {
+		time.Sleep(100 * time.Millisecond)
+		return nil, nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	begin := time.Now()
+	if err := l.Shutdown(); err != nil {
+		t.Fatalf("shutdown err(%v)", err)
+	}
+	if time.Since(begin) < 50*time.Millisecond {
+		t.Errorf("shutdown did not wait for inflight request")
+	}
+	want := []string{"register", "register", "drain", "deregister"}
+	got := reg.get()
+	if len(got) != len(want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("want %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package election 主节点选举统一接口, 实现见 redisLock / registry/etcd / registry/zookeeper
+package election
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCampaigning = errors.New("election: already campaigning") // 同一对象重复参选
+
+// Election 同一选举内只有一个副本成为主节点
+type Election interface {
+	// Campaign 参与选举, 阻塞直到 ctx 取消或调用 Resign; 失去主节点后自动重新参选, 退出时主动让位
+	Campaign(ctx context.Context) error
+	// Resign 让出主节点并停止参选
+	Resign(ctx context.Context) error
+	// IsLeader 当前是否为主节点
+	IsLeader() bool
+}
+
+type Options struct {
+	TTL           time.Duration             // 租约时间, 主节点宕机后最长该时间内重新选出, 默认10s
+	RetryInterval time.Duration             // 出错后重试间隔, redis 为非主节点轮询间隔, 默认1s
+	Value         string                    // 参选者标识, 默认随机
+	OnElected     func(ctx context.Context) // 成为主节点, ctx 在失去主节点时取消
+	OnRevoked     func()                    // 失去主节点
+}
+
+type Option func(*Options)
+
+func NewOptions(opts ...Option) Options {
+	opt := Options{
+		TTL:           10 * time.Second,
+		RetryInterval: time.Second,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// TTL sets the lease of the leadership
+func TTL(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.TTL = d
+		}
+	}
+}
+
+// RetryInterval sets the interval between campaigns after failures
+func RetryInterval(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.RetryInterval = d
+		}
+	}
+}
+
+// Value sets the identity of the candidate
+func Value(v string) Option {
+	return func(o *Options) {
+		o.Value = v
+	}
+}
+
+// OnElected sets the callback run when becoming leader
+func OnElected(fn func(ctx context.Context)) Option {
+	return func(o *Options) {
+		o.OnElected = fn
+	}
+}
+
+// OnRevoked sets the callback run when leadership is lost
+func OnRevoked(fn func()) Option {
+	return func(o *Options) {
+		o.OnRevoked = fn
+	}
+}
+
+// Status 主节点状态和回调, 供各实现复用
+type Status struct {
+	opts   Options
+	mtx    sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewStatus(opts Options) *Status {
+	return &Status{opts: opts}
+}
+
+func (s *Status) IsLeader() bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.cancel != nil
+}
+
+// Elected 成为主节点, 异步执行 OnElected
+func (s *Status) Elected() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.cancel, s.done = cancel, done
+	go func() {
+		defer close(done)
+		if s.opts.OnElected != nil {
+			s.opts.OnElected(ctx)
+		}
+	}()
+}
+
+// Revoked 失去主节点, 取消 OnElected 的 ctx 并等待其返回后执行 OnRevoked
+func (s *Status) Revoked() {
+	s.mtx.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mtx.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+	if s.opts.OnRevoked != nil {
+		s.opts.OnRevoked()
+	}
+}
+
+// Runner 管理 Campaign 的生命周期, Resign 时停止并等待其退出
+type Runner struct {
+	mtx    sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start 返回 Campaign 使用的 ctx, 已在运行时返回 false
+func (r *Runner) Start(ctx context.Context) (context.Context, bool) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cancel != nil {
+		return nil, false
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	r.done = make(chan struct{})
+	return ctx, true
+}
+
+// Finish Campaign 退出时调用
+func (r *Runner) Finish() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+		close(r.done)
+		r.cancel, r.done = nil, nil
+	}
+}
+
+// Stop 停止 Campaign 并等待退出
+func (r *Runner) Stop(ctx context.Context) error {
+	r.mtx.Lock()
+	cancel, done := r.cancel, r.done
+	r.mtx.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sleep 等待 d, ctx 取消时返回 false
+func Sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
@@ -0,0 +1,72 @@
+package election
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStatus(t *testing.T) {
+	elected, revoked := make(chan struct{}, 1), make(chan struct{}, 1)
+	var workerDone bool
+	s := NewStatus(NewOptions(
+		OnElected(func(ctx context.Context) {
+			elected <- struct{}{}
+			<-ctx.Done()
+			workerDone = true
+		}),
+		OnRevoked(func() {
+			revoked <- struct{}{}
+		}),
+	))
+	if s.IsLeader() {
+		t.Fatal("leader before elected")
+	}
+	s.Elected()
+	s.Elected()
+	<-elected
+	if !s.IsLeader() {
+		t.Fatal("not leader after elected")
+	}
+	s.Revoked()
+	if !workerDone {
+		t.Fatal("OnRevoked before OnElected returned")
+	}
+	<-revoked
+	if s.IsLeader() {
+		t.Fatal("leader after revoked")
+	}
+	// 重复 Revoked 不再回调
+	s.Revoked()
+	select {
+	case <-revoked:
+		t.Fatal("revoked twice")
+	default:
+	}
+}
+
+func TestRunner(t *testing.T) {
+	var r Runner
+	ctx, ok := r.Start(context.Background())
+	if !ok {
+		t.Fatal("start failed")
+	}
+	if _, ok = r.Start(context.Background()); ok {
+		t.Fatal("start twice")
+	}
+	go func() {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		r.Finish()
+	}()
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = r.Start(context.Background()); !ok {
+		t.Fatal("restart failed")
+	}
+	r.Finish()
+}
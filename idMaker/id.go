@@ -29,6 +29,7 @@ GenerateId  可使用到 2056 年
 |---------------64位----------------------|
 |-1-|----40---------|----13-----|----10---|
 |填充|--时间戳差值-----|-自定填充---|--自增数--|
+节点号随机生成, 多实例可能重复, 集群内唯一请使用 Snowflake
 */
 func GenerateId() int64 {
 	idMtx.Lock()
@@ -38,7 +39,7 @@ RETRY:
 	now := utils.GetMillisecond()
 	if idTimestamp == now {
 		idTick++
-		if idTick > MaxTick {
+		if idTick >= MaxTick {
 			time.Sleep(time.Duration(1) * time.Millisecond)
 			goto RETRY
 		}
@@ -0,0 +1,210 @@
+package idMaker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var ErrSegmentStore = errors.New("idMaker: segment store returned invalid range") // 号段存储返回的区间不合法
+
+// SegmentStore 持久化每个业务 tag 已分配的最大 id, 实现见 db/mysql/gorm / db/mysql/xorm / cache/goredis/redisId
+type SegmentStore interface {
+	// Reserve 为 tag 预留 step 个 id, 返回闭区间 [start, end], 多实例并发调用时区间不能重叠
+	Reserve(ctx context.Context, tag string, step int64) (int64, int64, error)
+}
+
+type SegmentConfig struct {
+	Step          int64         // 初始号段长度, 默认1000
+	MaxStep       int64         // 号段长度上限, 消耗过快时步长翻倍直到该值, 默认 Step*100, 小于 Step 时等于 Step
+	Duration      time.Duration // 期望一个号段的消耗时间, 快于该值步长翻倍, 慢于两倍步长减半, 默认15分钟
+	PrefetchRatio float64       // 当前号段使用比例达到该值时异步预取下一个号段, 默认0.1
+	Timeout       time.Duration // 单次 Reserve 超时, 默认3秒
+}
+
+func DefaultSegmentConfig() *SegmentConfig {
+	return &SegmentConfig{
+		Step:          1000,
+		MaxStep:       100000,
+		Duration:      15 * time.Minute,
+		PrefetchRatio: 0.1,
+		Timeout:       3 * time.Second,
+	}
+}
+
+func checkSegmentConfig(conf *SegmentConfig) error {
+	if conf.Step <= 0 || conf.PrefetchRatio < 0 || conf.PrefetchRatio > 1 {
+		return errors.New("config is err")
+	}
+	if conf.MaxStep <= 0 {
+		conf.MaxStep = conf.Step * 100
+	} else if conf.MaxStep < conf.Step {
+		conf.MaxStep = conf.Step
+	}
+	if conf.Duration <= 0 {
+		conf.Duration = 15 * time.Minute
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 3 * time.Second
+	}
+	return nil
+}
+
+/*
+SegmentIdMaker 号段模式, 按业务 tag 从存储批量预留 id 在本地分配
+id 连续递增, 重启后丢弃未用完的号段, 不会重复
+双缓冲: 当前号段使用到 PrefetchRatio 时异步加载下一个号段, 存储短暂不可用时不影响分配
+*/
+type SegmentIdMaker struct {
+	store SegmentStore
+	conf  SegmentConfig
+	mtx   sync.Mutex
+	gens  map[string]*SegmentGenerator
+}
+
+func NewSegmentIdMaker(store SegmentStore, conf *SegmentConfig) (*SegmentIdMaker, error) {
+	if store == nil {
+		return nil, errors.New("param is err")
+	}
+	if conf == nil {
+		conf = DefaultSegmentConfig()
+	}
+	if err := checkSegmentConfig(conf); err != nil {
+		return nil, err
+	}
+	return &SegmentIdMaker{store: store, conf: *conf, gens: make(map[string]*SegmentGenerator)}, nil
+}
+
+// Generator 返回 tag 对应的生成器, 同一 tag 共用一个
+func (m *SegmentIdMaker) Generator(tag string) *SegmentGenerator {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	g, ok := m.gens[tag]
+	if !ok {
+		g = newSegmentGenerator(tag, m.store, &m.conf)
+		m.gens[tag] = g
+	}
+	return g
+}
+
+func (m *SegmentIdMaker) Next(ctx context.Context, tag string) (int64, error) {
+	return m.Generator(tag).Next(ctx)
+}
+
+type segment struct {
+	start int64
+	cur   int64
+	end   int64
+}
+
+func (s *segment) remaining() int64 {
+	return s.end - s.cur + 1
+}
+
+type SegmentGenerator struct {
+	tag      string
+	store    SegmentStore
+	conf     *SegmentConfig
+	mtx      sync.Mutex
+	cur      *segment
+	next     *segment
+	loading  chan struct{}
+	err      error
+	step     int64
+	lastLoad time.Time
+}
+
+func newSegmentGenerator(tag string, store SegmentStore, conf *SegmentConfig) *SegmentGenerator {
+	return &SegmentGenerator{
+		tag:   tag,
+		store: store,
+		conf:  conf,
+		cur:   &segment{start: 1, cur: 1},
+		step:  conf.Step,
+	}
+}
+
+func (g *SegmentGenerator) Tag() string {
+	return g.tag
+}
+
+// Next 当前号段用完且下一个号段未就绪时阻塞等待加载
+func (g *SegmentGenerator) Next(ctx context.Context) (int64, error) {
+	g.mtx.Lock()
+	for {
+		if g.cur.remaining() > 0 {
+			id := g.cur.cur
+			g.cur.cur++
+			if g.next == nil && g.loading == nil &&
+				float64(g.cur.cur-g.cur.start) >= float64(g.cur.end-g.cur.start+1)*g.conf.PrefetchRatio {
+				g.load()
+			}
+			g.mtx.Unlock()
+			return id, nil
+		}
+		if g.next != nil {
+			g.cur, g.next = g.next, nil
+			continue
+		}
+		wait := g.load()
+		g.mtx.Unlock()
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-wait:
+		}
+		g.mtx.Lock()
+		if g.next == nil && g.err != nil {
+			err := g.err
+			g.mtx.Unlock()
+			return 0, err
+		}
+	}
+}
+
+// load 调用方持有锁, 返回加载完成时关闭的 channel
+func (g *SegmentGenerator) load() chan struct{} {
+	if g.loading == nil {
+		g.loading = make(chan struct{})
+		go g.fetch(g.loading, g.step)
+	}
+	return g.loading
+}
+
+func (g *SegmentGenerator) fetch(done chan struct{}, step int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.conf.Timeout)
+	start, end, err := g.store.Reserve(ctx, g.tag, step)
+	cancel()
+	if err == nil && (end < start || start <= 0) {
+		err = ErrSegmentStore
+	}
+	if err != nil {
+		log.Printf("segment tag(%v) reserve err(%+v)", g.tag, err)
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.err = err
+	if err == nil {
+		g.next = &segment{start: start, cur: start, end: end}
+		g.adjustStep()
+	}
+	g.loading = nil
+	close(done)
+}
+
+// adjustStep 按号段消耗速度调整步长, 调用方持有锁
+func (g *SegmentGenerator) adjustStep() {
+	now := time.Now()
+	if !g.lastLoad.IsZero() {
+		elapsed := now.Sub(g.lastLoad)
+		if elapsed < g.conf.Duration && g.step*2 <= g.conf.MaxStep {
+			g.step *= 2
+		} else if elapsed > 2*g.conf.Duration && g.step/2 >= g.conf.Step {
+			g.step /= 2
+		}
+	}
+	g.lastLoad = now
+}
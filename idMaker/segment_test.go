@@ -0,0 +1,114 @@
+package idMaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+type memSegmentStore struct {
+	mtx   sync.Mutex
+	max   map[string]int64
+	calls int
+	fail  bool
+}
+
+func (s *memSegmentStore) Reserve(ctx context.Context, tag string, step int64) (int64, int64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.calls++
+	if s.fail {
+		return 0, 0, errors.New("store down")
+	}
+	s.max[tag] += step
+	return s.max[tag] - step + 1, s.max[tag], nil
+}
+
+func TestSegmentIdMaker(t *testing.T) {
+	store := &memSegmentStore{max: map[string]int64{"pay": 1000}}
+	conf := DefaultSegmentConfig()
+	conf.Step = 10
+	conf.MaxStep = 10
+	m, err := NewSegmentIdMaker(store, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 多个 goroutine 并发取号, id 连续且不重复
+	var mtx sync.Mutex
+	seen := make(map[int64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id, err := m.Next(ctx, "order")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mtx.Lock()
+				seen[id] = true
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	for id := int64(1); id <= 800; id++ {
+		if !seen[id] {
+			t.Fatalf("id %d missing", id)
+		}
+	}
+
+	// 不同 tag 独立计数
+	id, err := m.Next(ctx, "pay")
+	if err != nil || id != 1001 {
+		t.Fatalf("id %d err %v", id, err)
+	}
+	if m.Generator("pay") != m.Generator("pay") {
+		t.Fatal("generator not shared")
+	}
+}
+
+func TestSegmentStoreDown(t *testing.T) {
+	store := &memSegmentStore{max: map[string]int64{}}
+	conf := DefaultSegmentConfig()
+	conf.Step = 10
+	conf.MaxStep = 10
+	m, _ := NewSegmentIdMaker(store, conf)
+	ctx := context.Background()
+	g := m.Generator("order")
+	if _, err := g.Next(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 等待预取完成, 存储故障后仍能用完已缓存的两个号段
+	for {
+		g.mtx.Lock()
+		ready := g.next != nil
+		g.mtx.Unlock()
+		if ready {
+			break
+		}
+	}
+	store.mtx.Lock()
+	store.fail = true
+	store.mtx.Unlock()
+	for i := 2; i <= 20; i++ {
+		id, err := g.Next(ctx)
+		if err != nil || id != int64(i) {
+			t.Fatalf("id %d err %v", id, err)
+		}
+	}
+	if _, err := g.Next(ctx); err == nil {
+		t.Fatal("want store error")
+	}
+	store.mtx.Lock()
+	store.fail = false
+	store.mtx.Unlock()
+	if id, err := g.Next(ctx); err != nil || id <= 20 {
+		t.Fatalf("id %d err %v", id, err)
+	}
+}
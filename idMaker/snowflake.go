@@ -0,0 +1,202 @@
+package idMaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClockRollback = errors.New("idMaker: clock moved backwards") // 时钟回拨超过 MaxRollback
+	ErrTimeOverflow  = errors.New("idMaker: timestamp overflow")    // 时间戳位数用完
+	ErrWorkerLost    = errors.New("idMaker: worker id lease lost")  // worker id 租约丢失
+)
+
+type SnowflakeConfig struct {
+	Epoch        time.Time     // 起始时间
+	TimeUnit     time.Duration // 时间戳单位, 默认1ms
+	TimeBits     uint8         // 时间戳位数, 默认41
+	WorkerBits   uint8         // worker id 位数, 默认10
+	SequenceBits uint8         // 序列号位数, 默认12
+	MaxRollback  time.Duration // 时钟回拨不超过该值时等待追上, 超过返回 ErrClockRollback, 默认10ms
+}
+
+func DefaultSnowflakeConfig() *SnowflakeConfig {
+	return &SnowflakeConfig{
+		Epoch:        time.UnixMilli(Epoch),
+		TimeUnit:     time.Millisecond,
+		TimeBits:     41,
+		WorkerBits:   10,
+		SequenceBits: 12,
+		MaxRollback:  10 * time.Millisecond,
+	}
+}
+
+func checkSnowflakeConfig(conf *SnowflakeConfig) error {
+	if conf.TimeUnit <= 0 {
+		conf.TimeUnit = time.Millisecond
+	}
+	if conf.TimeBits == 0 || conf.SequenceBits == 0 || int(conf.TimeBits)+int(conf.WorkerBits)+int(conf.SequenceBits) > 63 {
+		return errors.New("config is err")
+	}
+	return nil
+}
+
+// WorkerLeaser 租用集群内唯一的 worker id, 实现见 registry/etcd / registry/zookeeper / redisLock
+type WorkerLeaser interface {
+	// Acquire 在 [0, max) 中租用一个未被占用的 id, 持有期间自动续期
+	Acquire(ctx context.Context, max int64) (int64, error)
+	// Done 租约丢失时关闭, 此时其他实例可能获得同一个 id
+	Done() <-chan struct{}
+	// Release 释放租约
+	Release(ctx context.Context) error
+}
+
+/*
+Snowflake 位布局可配置
+|---------------64位--------------------------------|
+|-1-|--TimeBits--|--WorkerBits--|--SequenceBits----|
+|填充|-时间戳差值--|--worker id---|------自增数-------|
+*/
+type Snowflake struct {
+	conf     SnowflakeConfig
+	workerId int64
+	maxSeq   int64
+	maxTime  int64
+	leaser   WorkerLeaser
+	mtx      sync.Mutex
+	last     int64
+	seq      int64
+	lost     bool
+	now      func() time.Time
+}
+
+// NewSnowflake workerId 需由调用方保证唯一
+func NewSnowflake(conf *SnowflakeConfig, workerId int64) (*Snowflake, error) {
+	if conf == nil {
+		conf = DefaultSnowflakeConfig()
+	}
+	if err := checkSnowflakeConfig(conf); err != nil {
+		return nil, err
+	}
+	if workerId < 0 || workerId >= 1<<conf.WorkerBits {
+		return nil, errors.New("param is err")
+	}
+	return &Snowflake{
+		conf:     *conf,
+		workerId: workerId,
+		maxSeq:   1<<conf.SequenceBits - 1,
+		maxTime:  1<<conf.TimeBits - 1,
+		last:     -1,
+		now:      time.Now,
+	}, nil
+}
+
+// NewSnowflakeWithLeaser 通过 leaser 租用 worker id, 租约丢失后 Next 返回 ErrWorkerLost
+func NewSnowflakeWithLeaser(ctx context.Context, conf *SnowflakeConfig, leaser WorkerLeaser) (*Snowflake, error) {
+	if conf == nil {
+		conf = DefaultSnowflakeConfig()
+	}
+	if err := checkSnowflakeConfig(conf); err != nil {
+		return nil, err
+	}
+	workerId, err := leaser.Acquire(ctx, 1<<conf.WorkerBits)
+	if err != nil {
+		return nil, err
+	}
+	s, err := NewSnowflake(conf, workerId)
+	if err != nil {
+		_ = leaser.Release(ctx)
+		return nil, err
+	}
+	s.leaser = leaser
+	go func() {
+		<-leaser.Done()
+		s.mtx.Lock()
+		s.lost = true
+		s.mtx.Unlock()
+	}()
+	return s, nil
+}
+
+func (s *Snowflake) WorkerId() int64 {
+	return s.workerId
+}
+
+// Close 释放 worker id 租约
+func (s *Snowflake) Close(ctx context.Context) error {
+	if s.leaser == nil {
+		return nil
+	}
+	return s.leaser.Release(ctx)
+}
+
+func (s *Snowflake) tick() int64 {
+	return int64(s.now().Sub(s.conf.Epoch) / s.conf.TimeUnit)
+}
+
+// next 调用方持有锁
+func (s *Snowflake) next() (int64, error) {
+	if s.lost {
+		return 0, ErrWorkerLost
+	}
+	now := s.tick()
+	if now < s.last {
+		// 时钟回拨, 小幅回拨等待追上
+		if time.Duration(s.last-now)*s.conf.TimeUnit > s.conf.MaxRollback {
+			return 0, ErrClockRollback
+		}
+		for now < s.last {
+			time.Sleep(time.Duration(s.last-now) * s.conf.TimeUnit)
+			now = s.tick()
+		}
+	}
+	if now == s.last {
+		s.seq++
+		if s.seq > s.maxSeq {
+			// 当前时间单位内序列号用完, 等待下一个
+			for now <= s.last {
+				time.Sleep(s.conf.TimeUnit / 10)
+				now = s.tick()
+			}
+			s.seq = 0
+		}
+	} else {
+		s.seq = 0
+	}
+	if now > s.maxTime || now < 0 {
+		return 0, ErrTimeOverflow
+	}
+	s.last = now
+	return now<<(s.conf.WorkerBits+s.conf.SequenceBits) | s.workerId<<s.conf.SequenceBits | s.seq, nil
+}
+
+func (s *Snowflake) Next() (int64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.next()
+}
+
+// NextN 批量生成 n 个递增的 id
+func (s *Snowflake) NextN(n int) ([]int64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ids := make([]int64, 0, n)
+	for i := 0; i < n; i++ {
+		id, err := s.next()
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Parse 解析 id 的生成时间, worker id 和序列号
+func (s *Snowflake) Parse(id int64) (time.Time, int64, int64) {
+	seq := id & s.maxSeq
+	workerId := (id >> s.conf.SequenceBits) & (1<<s.conf.WorkerBits - 1)
+	tick := id >> (s.conf.WorkerBits + s.conf.SequenceBits)
+	return s.conf.Epoch.Add(time.Duration(tick) * s.conf.TimeUnit), workerId, seq
+}
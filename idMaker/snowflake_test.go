@@ -0,0 +1,101 @@
+package idMaker
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeLeaser struct {
+	id   int64
+	done chan struct{}
+}
+
+func (f *fakeLeaser) Acquire(ctx context.Context, max int64) (int64, error) {
+	return f.id, nil
+}
+
+func (f *fakeLeaser) Done() <-chan struct{} {
+	return f.done
+}
+
+func (f *fakeLeaser) Release(ctx context.Context) error {
+	return nil
+}
+
+func TestSnowflake(t *testing.T) {
+	conf := DefaultSnowflakeConfig()
+	conf.SequenceBits = 2
+	s, err := NewSnowflake(conf, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mtx sync.Mutex
+	now := conf.Epoch.Add(time.Hour)
+	s.now = func() time.Time {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return now
+	}
+	ids, err := s.NextN(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range ids {
+		ts, worker, seq := s.Parse(id)
+		if !ts.Equal(now) || worker != 5 || seq != int64(i) {
+			t.Fatalf("id %d parsed to %v %d %d", id, ts, worker, seq)
+		}
+	}
+	// 序列号用完时等待下一毫秒
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		mtx.Lock()
+		now = now.Add(time.Millisecond)
+		mtx.Unlock()
+	}()
+	id, err := s.Next()
+	if err != nil || id <= ids[3] {
+		t.Fatalf("id %d err %v", id, err)
+	}
+
+	// 大幅回拨返回错误
+	mtx.Lock()
+	now = now.Add(-time.Second)
+	mtx.Unlock()
+	if _, err = s.Next(); err != ErrClockRollback {
+		t.Fatalf("want ErrClockRollback, got %v", err)
+	}
+}
+
+func TestSnowflakeConfig(t *testing.T) {
+	conf := DefaultSnowflakeConfig()
+	conf.WorkerBits = 30
+	if _, err := NewSnowflake(conf, 1); err == nil {
+		t.Fatal("want config error")
+	}
+	if _, err := NewSnowflake(nil, 1024); err == nil {
+		t.Fatal("want worker id error")
+	}
+}
+
+func TestSnowflakeLeaser(t *testing.T) {
+	leaser := &fakeLeaser{id: 7, done: make(chan struct{})}
+	s, err := NewSnowflakeWithLeaser(context.Background(), nil, leaser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := s.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, worker, _ := s.Parse(id); worker != 7 {
+		t.Fatalf("worker %d", worker)
+	}
+	close(leaser.done)
+	time.Sleep(10 * time.Millisecond)
+	if _, err = s.Next(); err != ErrWorkerLost {
+		t.Fatalf("want ErrWorkerLost, got %v", err)
+	}
+}
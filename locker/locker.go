@@ -0,0 +1,84 @@
+// Package locker 分布式锁统一接口, 实现见 redisLock / registry/etcd / registry/zookeeper
+package locker
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotLocked = errors.New("locker: not locked")     // 未持有锁时解锁
+	ErrLockLost  = errors.New("locker: lock lost")      // 续期失败, 锁已被释放或抢占
+	ErrLocked    = errors.New("locker: already locked") // 同一对象重复加锁
+)
+
+// Locker 分布式锁, 持有期间自动续期
+type Locker interface {
+	// Lock 阻塞直到获取锁, ctx 取消或超过 Options.Timeout 时返回错误
+	Lock(ctx context.Context) error
+	// TryLock 尝试获取一次, 锁被占用时返回 false
+	TryLock(ctx context.Context) (bool, error)
+	// Unlock 释放锁并停止续期
+	Unlock(ctx context.Context) error
+}
+
+type Options struct {
+	TTL           time.Duration // 锁过期时间, 持有期间自动续期, 默认10s
+	Timeout       time.Duration // 获取锁超时时间, 为0只受ctx控制
+	RetryInterval time.Duration // 轮询重试间隔(redis), 默认50ms
+	Owner         string        // 持有者标识(redis), 默认随机, 相同标识可重入
+}
+
+type Option func(*Options)
+
+func NewOptions(opts ...Option) Options {
+	opt := Options{
+		TTL:           10 * time.Second,
+		RetryInterval: 50 * time.Millisecond,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	return opt
+}
+
+// TTL sets the lease of the lock
+func TTL(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.TTL = d
+		}
+	}
+}
+
+// Timeout sets the max time to wait for the lock
+func Timeout(d time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = d
+	}
+}
+
+// RetryInterval sets the polling interval for backends without watches
+func RetryInterval(d time.Duration) Option {
+	return func(o *Options) {
+		if d > 0 {
+			o.RetryInterval = d
+		}
+	}
+}
+
+// Owner sets the holder identity, lockers sharing it are reentrant
+func Owner(owner string) Option {
+	return func(o *Options) {
+		o.Owner = owner
+	}
+}
+
+// WithTimeout 按 Options.Timeout 为获取锁设置超时
+func WithTimeout(ctx context.Context, opts Options) (context.Context, context.CancelFunc) {
+	if opts.Timeout > 0 {
+		return context.WithTimeout(ctx, opts.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
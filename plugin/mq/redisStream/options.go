@@ -0,0 +1,78 @@
+package redisStream
+
+import (
+	goredis "github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/plugin/mq/broker"
+	"time"
+)
+
+type clientKey struct{}
+
+// Client 使用已有的客户端, Disconnect 时不会关闭它
+func Client(cli *goredis.GoRedis) broker.Option {
+	return setBrokerOption(clientKey{}, cli)
+}
+
+type configKey struct{}
+
+// Config 未指定 Client 时 Connect 按该配置创建客户端, 不指定时使用 Addrs 单节点
+func Config(conf *goredis.RedisConfig) broker.Option {
+	return setBrokerOption(configKey{}, conf)
+}
+
+type maxLenKey struct{}
+
+// MaxLen 发布时按条数裁剪 stream, 默认按节点近似裁剪
+func MaxLen(n int64) broker.Option {
+	return setBrokerOption(maxLenKey{}, n)
+}
+
+type exactTrimKey struct{}
+
+// ExactTrim 精确裁剪, 性能比近似裁剪差
+func ExactTrim() broker.Option {
+	return setBrokerOption(exactTrimKey{}, true)
+}
+
+type retentionKey struct{}
+
+// Retention 发布时删除早于该时长的消息, 每个 topic 每秒最多裁剪一次, 需要 redis 6.2 以上
+func Retention(d time.Duration) broker.Option {
+	return setBrokerOption(retentionKey{}, d)
+}
+
+type consumerKey struct{}
+
+// Consumer 消费者名, 默认随机生成
+// 固定名字重启后可继续处理自己未确认的消息, 同一消费组内的多个订阅不能相同
+func Consumer(name string) broker.SubscribeOption {
+	return setSubscribeOption(consumerKey{}, name)
+}
+
+type batchSizeKey struct{}
+
+// BatchSize 每次读取的消息数, 默认10
+func BatchSize(n int64) broker.SubscribeOption {
+	return setSubscribeOption(batchSizeKey{}, n)
+}
+
+type claimIdleKey struct{}
+
+// ClaimIdle 待确认消息空闲超过该时长时被其他消费者接管, 默认1分钟, 需大于消息的处理时长
+func ClaimIdle(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(claimIdleKey{}, d)
+}
+
+type claimIntervalKey struct{}
+
+// ClaimInterval 检查待确认消息的间隔, 默认30秒, 小于0不接管
+func ClaimInterval(d time.Duration) broker.SubscribeOption {
+	return setSubscribeOption(claimIntervalKey{}, d)
+}
+
+type startIdKey struct{}
+
+// StartId 新建消费组时开始消费的位置, 默认 $ 只消费之后发布的消息, 0 从头消费
+func StartId(id string) broker.SubscribeOption {
+	return setSubscribeOption(startIdKey{}, id)
+}
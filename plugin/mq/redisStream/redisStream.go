@@ -0,0 +1,458 @@
+// Package redisStream 基于 redis stream 消费组的 broker 实现
+// 同一 Queue 的订阅共享一个消费组, 每条消息只被其中一个订阅处理; 不指定 Queue 时每个订阅独占一个消费组, 收到全部消息
+// 处理失败或未确认的消息留在待确认列表, 空闲超过 ClaimIdle 后被组内的订阅接管重新处理
+package redisStream
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis"
+	"github.com/google/uuid"
+	goredis "github.com/y1015860449/gotoolkit/cache/goredis/redis"
+	"github.com/y1015860449/gotoolkit/plugin/mq/broker"
+	"github.com/y1015860449/gotoolkit/plugin/mq/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dataField = "data"
+	blockTime = 2 * time.Second
+)
+
+type rBroker struct {
+	addrs []string
+	cli   *goredis.GoRedis
+	own   bool // 客户端由 Connect 创建, Disconnect 时关闭
+
+	connected bool
+	mtx       sync.Mutex
+	subs      map[*subscriber]struct{}
+	trimmed   map[string]time.Time
+	opts      broker.Options
+}
+
+type subscriber struct {
+	b        *rBroker
+	t        string
+	group    string
+	consumer string
+	random   bool // 消费者名随机生成, 取消订阅时没有待确认消息则从消费组删除
+	unique   bool // 消费组只属于该订阅, 取消订阅时删除
+	start    string
+	batch    int64
+	idle     time.Duration
+	interval time.Duration
+	handler  broker.Handler
+	opts     broker.SubscribeOptions
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+type publication struct {
+	t   string
+	id  string
+	err error
+	m   *broker.Message
+	s   *subscriber
+}
+
+func (p *publication) Topic() string {
+	return p.t
+}
+
+func (p *publication) Message() *broker.Message {
+	return p.m
+}
+
+func (p *publication) Ack() error {
+	_, err := p.s.b.cli.XAck(p.t, p.s.group, p.id)
+	return err
+}
+
+func (p *publication) Error() error {
+	return p.err
+}
+
+func (s *subscriber) Options() broker.SubscribeOptions {
+	return s.opts
+}
+
+func (s *subscriber) Topic() string {
+	return s.t
+}
+
+// Unsubscribe 等待正在处理的消息结束, 独占的消费组会被删除;
+// 共享消费组中随机生成的消费者没有待确认消息时被删除, 有则保留, 由组内其他订阅接管
+func (s *subscriber) Unsubscribe() error {
+	var err error
+	s.once.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+		s.b.mtx.Lock()
+		delete(s.b.subs, s)
+		s.b.mtx.Unlock()
+		if s.unique {
+			_, err = s.b.cli.XGroupDestroy(s.t, s.group)
+			return
+		}
+		if s.random {
+			err = s.delConsumer()
+		}
+	})
+	return err
+}
+
+func (s *subscriber) delConsumer() error {
+	pending, err := s.b.cli.XPendingExt(s.t, s.group, s.consumer, "-", "+", 1)
+	if err != nil || len(pending) > 0 {
+		return err
+	}
+	_, err = s.b.cli.XGroupDelConsumer(s.t, s.group, s.consumer)
+	return err
+}
+
+func (s *subscriber) sleep(d time.Duration) {
+	select {
+	case <-s.ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+func (s *subscriber) run() {
+	defer s.wg.Done()
+	// 先处理本消费者之前未确认的消息
+	s.readPending()
+	lastClaim := time.Now()
+	for s.ctx.Err() == nil {
+		if s.interval > 0 && time.Since(lastClaim) >= s.interval {
+			s.claim()
+			lastClaim = time.Now()
+		}
+		// 取消后阻塞中的读取最长 blockTime 后返回, 读到的消息仍会处理
+		streams, err := s.b.cli.XReadGroupCtx(s.ctx, s.group, s.consumer, []string{s.t}, nil, s.batch, blockTime)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.onReadErr(err)
+			continue
+		}
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				s.handle(msg)
+			}
+		}
+	}
+}
+
+// onReadErr stream 或消费组被删除时重新创建
+func (s *subscriber) onReadErr(err error) {
+	if strings.HasPrefix(err.Error(), "NOGROUP") {
+		if err = s.b.cli.XGroupCreate(s.t, s.group, s.start); err == nil {
+			return
+		}
+	}
+	log.Printf("[redis]: topic(%v) group(%v) read err(%+v)", s.t, s.group, err)
+	s.sleep(time.Second)
+}
+
+func (s *subscriber) readPending() {
+	id := "0"
+	for s.ctx.Err() == nil {
+		streams, err := s.b.cli.XReadGroupCtx(s.ctx, s.group, s.consumer, []string{s.t}, []string{id}, s.batch, -1)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				log.Printf("[redis]: topic(%v) group(%v) read pending err(%+v)", s.t, s.group, err)
+			}
+			return
+		}
+		if len(streams) <= 0 || len(streams[0].Messages) <= 0 {
+			return
+		}
+		for _, msg := range streams[0].Messages {
+			s.handle(msg)
+			id = msg.ID
+		}
+	}
+}
+
+// claim 接管组内空闲超过 ClaimIdle 的消息, 包括自己处理失败的
+func (s *subscriber) claim() {
+	start := "0-0"
+	for s.ctx.Err() == nil {
+		msgs, deleted, next, err := s.b.cli.XAutoClaimCtx(s.ctx, s.t, s.group, s.consumer, s.idle, start, s.batch)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				log.Printf("[redis]: topic(%v) group(%v) claim err(%+v)", s.t, s.group, err)
+			}
+			return
+		}
+		// 已被裁剪删除的消息无法处理, 确认后移出待确认列表
+		if len(deleted) > 0 {
+			if _, err = s.b.cli.XAck(s.t, s.group, deleted...); err != nil {
+				log.Printf("[redis]: topic(%v) group(%v) ack deleted err(%+v)", s.t, s.group, err)
+			}
+		}
+		for _, msg := range msgs {
+			s.handle(msg)
+		}
+		if next == "" || next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+func (s *subscriber) handle(msg redis.XMessage) {
+	var m broker.Message
+	p := &publication{t: s.t, id: msg.ID, m: &m, s: s}
+	eh := s.b.opts.ErrorHandler
+
+	data, _ := msg.Values[dataField].(string)
+	if err := s.b.opts.Codec.Unmarshal([]byte(data), &m); err != nil {
+		p.err = err
+		p.m.Body = []byte(data)
+		if eh != nil {
+			eh(p)
+		} else {
+			log.Printf("[redis]: failed to unmarshal: %v", err)
+		}
+		// 无法解析的消息重试也不会成功, 直接确认避免反复接管
+		if err = p.Ack(); err != nil {
+			log.Printf("[redis]: topic(%v) ack id(%v) err(%+v)", s.t, msg.ID, err)
+		}
+		return
+	}
+
+	err := s.handler(p)
+	if err == nil && s.opts.AutoAck {
+		if err = p.Ack(); err != nil {
+			log.Printf("[redis]: topic(%v) ack id(%v) err(%+v)", s.t, msg.ID, err)
+		}
+	} else if err != nil {
+		p.err = err
+		if eh != nil {
+			eh(p)
+		} else {
+			log.Printf("[redis]: subscriber error: %v", err)
+		}
+	}
+}
+
+func (r *rBroker) Address() string {
+	if len(r.addrs) > 0 {
+		return r.addrs[0]
+	}
+	return "127.0.0.1:6379"
+}
+
+func (r *rBroker) Connect() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.connected {
+		return nil
+	}
+	if cli, ok := r.opts.Context.Value(clientKey{}).(*goredis.GoRedis); ok && cli != nil {
+		r.cli, r.own = cli, false
+	} else {
+		conf, ok := r.opts.Context.Value(configKey{}).(*goredis.RedisConfig)
+		if !ok || conf == nil {
+			conf = &goredis.RedisConfig{Addr: r.addrs}
+		}
+		cli, err := goredis.InitRedis(conf)
+		if err != nil {
+			return err
+		}
+		r.cli, r.own = cli, true
+	}
+	r.subs = make(map[*subscriber]struct{})
+	r.trimmed = make(map[string]time.Time)
+	r.connected = true
+	return nil
+}
+
+// Disconnect 取消所有订阅
+func (r *rBroker) Disconnect() error {
+	r.mtx.Lock()
+	if !r.connected {
+		r.mtx.Unlock()
+		return nil
+	}
+	subs := make([]*subscriber, 0, len(r.subs))
+	for s := range r.subs {
+		subs = append(subs, s)
+	}
+	r.mtx.Unlock()
+
+	for _, s := range subs {
+		if err := s.Unsubscribe(); err != nil {
+			log.Printf("[redis]: topic(%v) unsubscribe err(%+v)", s.t, err)
+		}
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	var err error
+	if r.own {
+		err = r.cli.Close()
+	}
+	r.connected = false
+	return err
+}
+
+func (r *rBroker) Init(opts ...broker.Option) error {
+	for _, o := range opts {
+		o(&r.opts)
+	}
+	r.addrs = validAddrs(r.opts.Addrs)
+	return nil
+}
+
+func (r *rBroker) Options() broker.Options {
+	return r.opts
+}
+
+func (r *rBroker) client() (*goredis.GoRedis, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if !r.connected {
+		return nil, errors.New("not connected")
+	}
+	return r.cli, nil
+}
+
+func (r *rBroker) Publish(topic string, msg *broker.Message, opts ...broker.PublishOption) error {
+	cli, err := r.client()
+	if err != nil {
+		return err
+	}
+	b, err := r.opts.Codec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	maxLen, _ := r.opts.Context.Value(maxLenKey{}).(int64)
+	exact, _ := r.opts.Context.Value(exactTrimKey{}).(bool)
+	if _, err = cli.XAdd(topic, maxLen, !exact, map[string]interface{}{dataField: b}); err != nil {
+		return err
+	}
+	r.trim(cli, topic, !exact)
+	return nil
+}
+
+// trim 按 Retention 删除旧消息, 失败只记录日志
+func (r *rBroker) trim(cli *goredis.GoRedis, topic string, approx bool) {
+	retention, _ := r.opts.Context.Value(retentionKey{}).(time.Duration)
+	if retention <= 0 {
+		return
+	}
+	now := time.Now()
+	r.mtx.Lock()
+	if now.Sub(r.trimmed[topic]) < time.Second {
+		r.mtx.Unlock()
+		return
+	}
+	r.trimmed[topic] = now
+	r.mtx.Unlock()
+	minId := strconv.FormatInt(now.Add(-retention).UnixMilli(), 10) + "-0"
+	if _, err := cli.XTrimMinId(topic, minId, approx); err != nil {
+		log.Printf("[redis]: topic(%v) trim err(%+v)", topic, err)
+	}
+}
+
+func (r *rBroker) Subscribe(topic string, handler broker.Handler, opts ...broker.SubscribeOption) (broker.Subscriber, error) {
+	opt := broker.SubscribeOptions{
+		AutoAck: true,
+		Context: context.Background(),
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	cli, err := r.client()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &subscriber{
+		b:        r,
+		t:        topic,
+		group:    opt.Queue,
+		consumer: uuid.New().String(),
+		random:   true,
+		start:    "$",
+		batch:    10,
+		idle:     time.Minute,
+		interval: 30 * time.Second,
+		handler:  handler,
+		opts:     opt,
+	}
+	if len(s.group) <= 0 {
+		s.group, s.unique = uuid.New().String(), true
+	}
+	if v, ok := opt.Context.Value(consumerKey{}).(string); ok && len(v) > 0 {
+		s.consumer, s.random = v, false
+	}
+	if v, ok := opt.Context.Value(startIdKey{}).(string); ok && len(v) > 0 {
+		s.start = v
+	}
+	if v, ok := opt.Context.Value(batchSizeKey{}).(int64); ok && v > 0 {
+		s.batch = v
+	}
+	if v, ok := opt.Context.Value(claimIdleKey{}).(time.Duration); ok && v > 0 {
+		s.idle = v
+	}
+	if v, ok := opt.Context.Value(claimIntervalKey{}).(time.Duration); ok && v != 0 {
+		s.interval = v
+	}
+	if err = cli.XGroupCreate(topic, s.group, s.start); err != nil {
+		return nil, err
+	}
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	r.mtx.Lock()
+	r.subs[s] = struct{}{}
+	r.mtx.Unlock()
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (r *rBroker) BrokerName() string {
+	return "redis"
+}
+
+func validAddrs(addrs []string) []string {
+	var cAddrs []string
+	for _, addr := range addrs {
+		if len(addr) == 0 {
+			continue
+		}
+		cAddrs = append(cAddrs, addr)
+	}
+	if len(cAddrs) == 0 {
+		cAddrs = []string{"127.0.0.1:6379"}
+	}
+	return cAddrs
+}
+
+func NewBroker(opts ...broker.Option) broker.Broker {
+	options := broker.Options{
+		// default to json codec
+		Codec:   json.Marshaler{},
+		Context: context.Background(),
+	}
+
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &rBroker{
+		addrs: validAddrs(options.Addrs),
+		opts:  options,
+	}
+}
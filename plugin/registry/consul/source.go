@@ -0,0 +1,71 @@
+package consul
+
+import (
+	"context"
+	consulApi "github.com/hashicorp/consul/api"
+	"github.com/y1015860449/gotoolkit/config"
+	"log"
+	"time"
+)
+
+// ConfigSource consul KV 配置, 阻塞查询监听变化, 作为 config.Source 使用
+type ConfigSource struct {
+	cli   *ConsulClient
+	key   string
+	index uint64
+}
+
+func (cli *ConsulClient) ConfigSource(key string) *ConfigSource {
+	return &ConfigSource{cli: cli, key: key}
+}
+
+func (s *ConfigSource) get(opts *consulApi.QueryOptions) ([]byte, uint64, error) {
+	pair, meta, err := s.cli.client.KV().Get(s.key, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if pair == nil {
+		return nil, meta.LastIndex, nil
+	}
+	return pair.Value, meta.LastIndex, nil
+}
+
+func (s *ConfigSource) Read() ([]byte, error) {
+	data, index, err := s.get(nil)
+	if err != nil {
+		return nil, err
+	}
+	s.index = index
+	return data, nil
+}
+
+func (s *ConfigSource) Watch(ctx context.Context, onChange func(data []byte)) error {
+	go func() {
+		index := s.index
+		for ctx.Err() == nil {
+			opts := &consulApi.QueryOptions{WaitIndex: index, WaitTime: 5 * time.Minute}
+			data, last, err := s.get(opts.WithContext(ctx))
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("config watch key(%v) err(%+v)", s.key, err)
+					select {
+					case <-ctx.Done():
+					case <-time.After(time.Second):
+					}
+				}
+				continue
+			}
+			switch {
+			case last < index:
+				// 索引回退时重置
+				index = 0
+			case last > index:
+				index = last
+				onChange(data)
+			}
+		}
+	}()
+	return nil
+}
+
+var _ config.Source = (*ConfigSource)(nil)
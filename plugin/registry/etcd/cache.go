@@ -0,0 +1,99 @@
+package etcd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache 前缀的本地只读缓存, 创建时全量加载, 之后由 WatchPrefix 保持同步
+type Cache struct {
+	cli      *EtcdClient
+	prefix   string
+	mtx      sync.RWMutex
+	kvs      map[string]KeyValue
+	rev      int64
+	cancel   context.CancelFunc
+	onChange func(WatchResponse)
+}
+
+// NewCache onChange 可为空, 在缓存更新后回调
+func (cli *EtcdClient) NewCache(prefix string, onChange func(WatchResponse)) (*Cache, error) {
+	kvs, rev, err := cli.List(prefix, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cache{
+		cli:      cli,
+		prefix:   prefix,
+		kvs:      make(map[string]KeyValue, len(kvs)),
+		rev:      rev,
+		cancel:   cancel,
+		onChange: onChange,
+	}
+	for _, kv := range kvs {
+		c.kvs[kv.Key] = kv
+	}
+	cli.WatchPrefix(ctx, prefix, rev+1, c.apply)
+	return c, nil
+}
+
+func (c *Cache) apply(resp WatchResponse) {
+	c.mtx.Lock()
+	if resp.Reset {
+		c.kvs = make(map[string]KeyValue, len(resp.Events))
+	}
+	for _, ev := range resp.Events {
+		if ev.Type == EventDelete {
+			delete(c.kvs, ev.Key)
+		} else {
+			c.kvs[ev.Key] = ev.KeyValue
+		}
+	}
+	c.rev = resp.Revision
+	c.mtx.Unlock()
+	if c.onChange != nil {
+		c.onChange(resp)
+	}
+}
+
+// Get 前缀外的 key 直接读取 etcd
+func (c *Cache) Get(key string) (string, bool, error) {
+	if !strings.HasPrefix(key, c.prefix) {
+		ctx, cancel := withTimeout(5 * time.Second)
+		defer cancel()
+		resp, err := c.cli.etcdCli.Get(ctx, key)
+		if err != nil || len(resp.Kvs) <= 0 {
+			return "", false, err
+		}
+		return string(resp.Kvs[0].Value), true, nil
+	}
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	kv, ok := c.kvs[key]
+	return kv.Value, ok, nil
+}
+
+// List 缓存中所有 key/value
+func (c *Cache) List() map[string]string {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	m := make(map[string]string, len(c.kvs))
+	for k, kv := range c.kvs {
+		m[k] = kv.Value
+	}
+	return m
+}
+
+// Revision 缓存已同步到的版本
+func (c *Cache) Revision() int64 {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.rev
+}
+
+func (c *Cache) Close() {
+	c.cancel()
+}
@@ -0,0 +1,93 @@
+package etcd
+
+import (
+	"context"
+	"github.com/y1015860449/gotoolkit/election"
+	"github.com/y1015860449/gotoolkit/utils"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"log"
+	"time"
+)
+
+// Election 基于 concurrency.Election 的选举, session 租约过期即失去主节点
+type Election struct {
+	*election.Status
+	cli    *EtcdClient
+	prefix string
+	opts   election.Options
+	runner election.Runner
+}
+
+func (cli *EtcdClient) NewElection(prefix string, opts ...election.Option) *Election {
+	o := election.NewOptions(opts...)
+	if len(o.Value) <= 0 {
+		o.Value = utils.GetUUID()
+	}
+	return &Election{
+		Status: election.NewStatus(o),
+		cli:    cli,
+		prefix: prefix,
+		opts:   o,
+	}
+}
+
+func (e *Election) Campaign(ctx context.Context) error {
+	ctx, ok := e.runner.Start(ctx)
+	if !ok {
+		return election.ErrCampaigning
+	}
+	defer e.runner.Finish()
+	ttl := int(e.opts.TTL.Seconds())
+	if ttl <= 0 {
+		ttl = 1
+	}
+	for ctx.Err() == nil {
+		session, err := concurrency.NewSession(e.cli.etcdCli, concurrency.WithTTL(ttl), concurrency.WithContext(context.Background()))
+		if err != nil {
+			log.Printf("election new session err(%+v)", err)
+			election.Sleep(ctx, e.opts.RetryInterval)
+			continue
+		}
+		el := concurrency.NewElection(session, e.prefix)
+		if err = el.Campaign(ctx, e.opts.Value); err != nil {
+			_ = session.Close()
+			if ctx.Err() == nil {
+				log.Printf("election campaign err(%+v)", err)
+				election.Sleep(ctx, e.opts.RetryInterval)
+			}
+			continue
+		}
+		e.Elected()
+		select {
+		case <-ctx.Done():
+			e.Revoked()
+			resignCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err = el.Resign(resignCtx); err != nil {
+				log.Printf("election resign err(%+v)", err)
+			}
+			cancel()
+		case <-session.Done():
+			log.Printf("election session(%v) lost", e.prefix)
+			e.Revoked()
+		}
+		_ = session.Close()
+	}
+	return nil
+}
+
+func (e *Election) Resign(ctx context.Context) error {
+	return e.runner.Stop(ctx)
+}
+
+// Leader 当前主节点的标识
+func (e *Election) Leader(ctx context.Context) (string, error) {
+	resp, err := e.cli.etcdCli.Get(ctx, e.prefix+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Kvs) <= 0 {
+		return "", concurrency.ErrElectionNoLeader
+	}
+	return string(resp.Kvs[0].Value), nil
+}
@@ -47,8 +47,9 @@ func (cli *EtcdClient) Put(key, metadata string, timeout time.Duration) error {
 	if timeout <= 0 {
 		timeout = 1 * time.Second
 	}
-	ctx, _ := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	_, err := cli.etcdCli.Put(ctx, key, metadata)
+	cancel()
 	if err != nil {
 		return err
 	}
@@ -111,6 +112,7 @@ func (cli *EtcdClient) Delete(key string, timeout time.Duration) error {
 	return nil
 }
 
+// Watch 不可取消, 断线期间的事件会丢失, 新代码使用 WatchPrefix
 func (cli *EtcdClient) Watch(key string, cb func(int32, string, string)) {
 	go func() {
 		watchKeys := cli.etcdCli.Watch(context.Background(), key)
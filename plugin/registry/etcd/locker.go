@@ -0,0 +1,109 @@
+package etcd
+
+import (
+	"context"
+	"github.com/y1015860449/gotoolkit/locker"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"sync"
+)
+
+// Locker 基于 concurrency.Mutex 的分布式锁, session 租约自动续期
+type Locker struct {
+	cli     *EtcdClient
+	key     string
+	opts    locker.Options
+	mtx     sync.Mutex
+	session *concurrency.Session
+	mutex   *concurrency.Mutex
+}
+
+func (cli *EtcdClient) NewLocker(key string, opts ...locker.Option) *Locker {
+	return &Locker{
+		cli:  cli,
+		key:  key,
+		opts: locker.NewOptions(opts...),
+	}
+}
+
+// newMutex 租约用调用方的 ctx 申请, etcd 不可达时不会超出 ctx 阻塞; session 续期不受 ctx 影响
+func (l *Locker) newMutex(ctx context.Context) (*concurrency.Session, *concurrency.Mutex, error) {
+	ttl := int(l.opts.TTL.Seconds())
+	if ttl <= 0 {
+		ttl = 1
+	}
+	lease, err := l.cli.etcdCli.Grant(ctx, int64(ttl))
+	if err != nil {
+		return nil, nil, err
+	}
+	session, err := concurrency.NewSession(l.cli.etcdCli, concurrency.WithLease(lease.ID), concurrency.WithContext(context.Background()))
+	if err != nil {
+		_, _ = l.cli.etcdCli.Revoke(ctx, lease.ID)
+		return nil, nil, err
+	}
+	return session, concurrency.NewMutex(session, l.key), nil
+}
+
+func (l *Locker) Lock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.mutex != nil {
+		return locker.ErrLocked
+	}
+	ctx, cancel := locker.WithTimeout(ctx, l.opts)
+	defer cancel()
+	session, mutex, err := l.newMutex(ctx)
+	if err != nil {
+		return err
+	}
+	if err = mutex.Lock(ctx); err != nil {
+		_ = session.Close()
+		return err
+	}
+	l.session, l.mutex = session, mutex
+	return nil
+}
+
+func (l *Locker) TryLock(ctx context.Context) (bool, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.mutex != nil {
+		return false, locker.ErrLocked
+	}
+	session, mutex, err := l.newMutex(ctx)
+	if err != nil {
+		return false, err
+	}
+	if err = mutex.TryLock(ctx); err != nil {
+		_ = session.Close()
+		if err == concurrency.ErrLocked {
+			return false, nil
+		}
+		return false, err
+	}
+	l.session, l.mutex = session, mutex
+	return true, nil
+}
+
+func (l *Locker) Unlock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.mutex == nil {
+		return locker.ErrNotLocked
+	}
+	session, mutex := l.session, l.mutex
+	l.session, l.mutex = nil, nil
+	err := mutex.Unlock(ctx)
+	// 关闭 session 会撤销租约, 即使 Unlock 失败锁也会释放
+	_ = session.Close()
+	return err
+}
+
+// Done 租约丢失(续期失败)时关闭, 此时锁已失效
+func (l *Locker) Done() <-chan struct{} {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.session == nil {
+		return nil
+	}
+	return l.session.Done()
+}
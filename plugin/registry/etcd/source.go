@@ -0,0 +1,80 @@
+package etcd
+
+import (
+	"context"
+	"github.com/y1015860449/gotoolkit/config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"log"
+	"time"
+)
+
+// ConfigSource etcd 单个 key 的配置, 作为 config.Source 使用
+type ConfigSource struct {
+	cli *EtcdClient
+	key string
+	rev int64
+}
+
+func (cli *EtcdClient) ConfigSource(key string) *ConfigSource {
+	return &ConfigSource{cli: cli, key: key}
+}
+
+func (s *ConfigSource) Read() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := s.cli.etcdCli.Get(ctx, s.key)
+	if err != nil {
+		return nil, err
+	}
+	s.rev = resp.Header.Revision
+	if len(resp.Kvs) <= 0 {
+		return nil, nil
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+func (s *ConfigSource) Watch(ctx context.Context, onChange func(data []byte)) error {
+	go func() {
+		// 从 Read 的版本之后开始监听, 避免漏掉中间的修改
+		rev := s.rev + 1
+		for ctx.Err() == nil {
+			rev = s.watch(ctx, rev, onChange)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+	return nil
+}
+
+// watch 返回下次应从哪个版本开始监听, 返回时关闭本次的 watch 流
+func (s *ConfigSource) watch(ctx context.Context, rev int64, onChange func(data []byte)) int64 {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wch := s.cli.etcdCli.Watch(clientv3.WithRequireLeader(ctx), s.key, clientv3.WithRev(rev))
+	for resp := range wch {
+		if err := resp.Err(); err != nil {
+			log.Printf("config watch key(%v) err(%+v)", s.key, err)
+			if resp.CompactRevision > 0 {
+				// 历史版本已压缩, 重新读取当前值后继续监听
+				if data, err := s.Read(); err == nil {
+					rev = s.rev + 1
+					onChange(data)
+				}
+			}
+			return rev
+		}
+		for _, ev := range resp.Events {
+			rev = ev.Kv.ModRevision + 1
+			if ev.Type == clientv3.EventTypeDelete {
+				onChange(nil)
+			} else {
+				onChange(ev.Kv.Value)
+			}
+		}
+	}
+	return rev
+}
+
+var _ config.Source = (*ConfigSource)(nil)
@@ -0,0 +1,68 @@
+package etcd
+
+import (
+	"context"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"time"
+)
+
+// CompareAndSwap 当前值等于 old 时更新为 value, old 为空表示 key 不存在时创建; 失败时返回当前值
+func (cli *EtcdClient) CompareAndSwap(key, old, value string, timeout time.Duration) (bool, string, error) {
+	cmp := clientv3.Compare(clientv3.Value(key), "=", old)
+	if len(old) <= 0 {
+		cmp = clientv3.Compare(clientv3.CreateRevision(key), "=", 0)
+	}
+	return cli.txnPut(key, value, cmp, timeout)
+}
+
+// CompareRevisionAndSwap 当前修改版本等于 rev 时更新, rev 为0表示 key 不存在时创建, 用于乐观锁
+func (cli *EtcdClient) CompareRevisionAndSwap(key, value string, rev int64, timeout time.Duration) (bool, string, error) {
+	return cli.txnPut(key, value, clientv3.Compare(clientv3.ModRevision(key), "=", rev), timeout)
+}
+
+// CompareAndDelete 当前值等于 old 时删除
+func (cli *EtcdClient) CompareAndDelete(key, old string, timeout time.Duration) (bool, error) {
+	ctx, cancel := withTimeout(timeout)
+	defer cancel()
+	resp, err := cli.etcdCli.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(key), "=", old)).
+		Then(clientv3.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
+
+// Txn 原始事务, 用于多个 key 的比较和操作
+func (cli *EtcdClient) Txn(ctx context.Context) clientv3.Txn {
+	return cli.etcdCli.Txn(ctx)
+}
+
+func (cli *EtcdClient) txnPut(key, value string, cmp clientv3.Cmp, timeout time.Duration) (bool, string, error) {
+	ctx, cancel := withTimeout(timeout)
+	defer cancel()
+	resp, err := cli.etcdCli.Txn(ctx).
+		If(cmp).
+		Then(clientv3.OpPut(key, value)).
+		Else(clientv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return false, "", err
+	}
+	if resp.Succeeded {
+		return true, value, nil
+	}
+	var cur string
+	if rng := resp.Responses[0].GetResponseRange(); rng != nil && len(rng.Kvs) > 0 {
+		cur = string(rng.Kvs[0].Value)
+	}
+	return false, cur, nil
+}
+
+func withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
@@ -0,0 +1,127 @@
+package etcd
+
+import (
+	"context"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"log"
+	"time"
+)
+
+const (
+	EventPut    int32 = 0
+	EventDelete int32 = 1
+)
+
+type KeyValue struct {
+	Key         string
+	Value       string
+	Version     int64
+	ModRevision int64
+}
+
+type Event struct {
+	Type int32 // EventPut/EventDelete
+	KeyValue
+}
+
+type WatchResponse struct {
+	Reset    bool  // 历史版本已被压缩, Events 为当前全量数据(均为 Put), 需替换本地状态
+	Revision int64 // 已处理到的版本
+	Events   []Event
+}
+
+// List 前缀下的所有 key/value 及读取时的版本
+func (cli *EtcdClient) List(prefix string, timeout time.Duration) ([]KeyValue, int64, error) {
+	if timeout <= 0 {
+		timeout = 1 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := cli.etcdCli.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make([]KeyValue, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs = append(kvs, KeyValue{
+			Key:         string(kv.Key),
+			Value:       string(kv.Value),
+			Version:     kv.Version,
+			ModRevision: kv.ModRevision,
+		})
+	}
+	return kvs, resp.Header.Revision, nil
+}
+
+// WatchPrefix 监听前缀, 从 rev 开始(<=0 为当前版本之后), ctx 取消时停止.
+// 断线重连后从最后处理的版本继续, 版本已被压缩时回调一次 Reset 全量数据后继续监听
+func (cli *EtcdClient) WatchPrefix(ctx context.Context, prefix string, rev int64, cb func(WatchResponse)) {
+	go func() {
+		for ctx.Err() == nil {
+			if rev <= 0 {
+				resp, err := cli.etcdCli.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithCountOnly())
+				if err != nil {
+					log.Printf("etcd watch prefix(%v) get revision err(%+v)", prefix, err)
+					cli.sleep(ctx)
+					continue
+				}
+				rev = resp.Header.Revision + 1
+			}
+			rev = cli.watch(ctx, prefix, rev, cb)
+			cli.sleep(ctx)
+		}
+	}()
+}
+
+// watch 返回下次应从哪个版本开始监听, 返回时关闭本次的 watch 流
+func (cli *EtcdClient) watch(ctx context.Context, prefix string, rev int64, cb func(WatchResponse)) int64 {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	wch := cli.etcdCli.Watch(clientv3.WithRequireLeader(ctx), prefix, clientv3.WithPrefix(), clientv3.WithRev(rev))
+	for resp := range wch {
+		if resp.CompactRevision > 0 {
+			log.Printf("etcd watch prefix(%v) compacted at(%d), resync", prefix, resp.CompactRevision)
+			kvs, cur, err := cli.List(prefix, 5*time.Second)
+			if err != nil {
+				log.Printf("etcd watch prefix(%v) resync err(%+v)", prefix, err)
+				return rev
+			}
+			events := make([]Event, 0, len(kvs))
+			for _, kv := range kvs {
+				events = append(events, Event{Type: EventPut, KeyValue: kv})
+			}
+			cb(WatchResponse{Reset: true, Revision: cur, Events: events})
+			return cur + 1
+		}
+		if err := resp.Err(); err != nil {
+			log.Printf("etcd watch prefix(%v) err(%+v)", prefix, err)
+			return rev
+		}
+		if len(resp.Events) <= 0 {
+			continue
+		}
+		events := make([]Event, 0, len(resp.Events))
+		for _, ev := range resp.Events {
+			events = append(events, Event{
+				Type: int32(ev.Type),
+				KeyValue: KeyValue{
+					Key:         string(ev.Kv.Key),
+					Value:       string(ev.Kv.Value),
+					Version:     ev.Kv.Version,
+					ModRevision: ev.Kv.ModRevision,
+				},
+			})
+		}
+		last := resp.Events[len(resp.Events)-1].Kv.ModRevision
+		rev = last + 1
+		cb(WatchResponse{Revision: last, Events: events})
+	}
+	return rev
+}
+
+func (cli *EtcdClient) sleep(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+	}
+}
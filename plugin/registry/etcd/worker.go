@@ -0,0 +1,129 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// WorkerLeaser 以租约 key prefix/<id> 占用 worker id, 租约过期即释放, 实现 idMaker.WorkerLeaser
+type WorkerLeaser struct {
+	cli     *EtcdClient
+	prefix  string
+	ttl     int64
+	mtx     sync.Mutex
+	leaseId clientv3.LeaseID
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewWorkerLeaser ttl 为租约时间, 实例宕机后该时间内 id 不会被复用
+func (cli *EtcdClient) NewWorkerLeaser(prefix string, ttl time.Duration) *WorkerLeaser {
+	sec := int64(ttl.Seconds())
+	if sec <= 0 {
+		sec = 10
+	}
+	return &WorkerLeaser{cli: cli, prefix: prefix, ttl: sec}
+}
+
+func (w *WorkerLeaser) Acquire(ctx context.Context, max int64) (int64, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.cancel != nil {
+		return 0, errors.New("worker id already acquired")
+	}
+	sent := time.Now()
+	lease, err := w.cli.etcdCli.Grant(ctx, w.ttl)
+	if err != nil {
+		return 0, err
+	}
+	host, _ := os.Hostname()
+	// 随机起点, 减少多个实例同时启动时的冲突
+	start := rand.Int63n(max)
+	for i := int64(0); i < max; i++ {
+		id := (start + i) % max
+		key := fmt.Sprintf("%s/%d", w.prefix, id)
+		resp, err := w.cli.etcdCli.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+			Then(clientv3.OpPut(key, host, clientv3.WithLease(lease.ID))).
+			Commit()
+		if err != nil {
+			_, _ = w.cli.etcdCli.Revoke(context.Background(), lease.ID)
+			return 0, err
+		}
+		if !resp.Succeeded {
+			continue
+		}
+		kctx, cancel := context.WithCancel(context.Background())
+		// 每次租用新建 done, Release 后可以再次 Acquire
+		w.leaseId, w.cancel, w.done = lease.ID, cancel, make(chan struct{})
+		go w.keepAlive(kctx, lease.ID, w.done, sent)
+		return id, nil
+	}
+	_, _ = w.cli.etcdCli.Revoke(context.Background(), lease.ID)
+	return 0, errors.New("no free worker id")
+}
+
+// keepAlive 每 TTL/3 续期一次, 租约失效或续期失败时关闭 done; 从最后一次成功续期的发送时刻起,
+// 在 TTL 前预留一个续期间隔即判定丢失, 保证租约过期前停止使用该 id
+func (w *WorkerLeaser) keepAlive(ctx context.Context, id clientv3.LeaseID, done chan struct{}, renewed time.Time) {
+	defer close(done)
+	ttl := time.Duration(w.ttl) * time.Second
+	interval := ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	expire := time.NewTimer(ttl - interval - time.Since(renewed))
+	defer expire.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-expire.C:
+			log.Printf("worker lease(%x) expired", id)
+			return
+		case <-ticker.C:
+			sent := time.Now()
+			rctx, cancel := context.WithTimeout(ctx, interval)
+			_, err := w.cli.etcdCli.KeepAliveOnce(rctx, id)
+			cancel()
+			if err == rpctypes.ErrLeaseNotFound {
+				log.Printf("worker lease(%x) lost", id)
+				return
+			}
+			if err != nil {
+				log.Printf("worker lease(%x) renew err(%+v)", id, err)
+				continue
+			}
+			if !expire.Stop() {
+				<-expire.C
+			}
+			expire.Reset(ttl - interval - time.Since(sent))
+		}
+	}
+}
+
+// Done 当前租约丢失或释放时关闭, Acquire 之前返回 nil
+func (w *WorkerLeaser) Done() <-chan struct{} {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.done
+}
+
+func (w *WorkerLeaser) Release(ctx context.Context) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.cancel == nil {
+		return nil
+	}
+	w.cancel()
+	w.cancel = nil
+	_, err := w.cli.etcdCli.Revoke(ctx, w.leaseId)
+	return err
+}
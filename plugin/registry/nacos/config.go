@@ -69,6 +69,7 @@ var (
 	Config = ExternalConfig{}
 )
 
+// InitConfig 解析到全局 Config, 需要热更新或自定义结构时使用 config.New 与 ConfigSource
 func InitConfig(data []byte) (*ExternalConfig, error) {
 	err := v2.Unmarshal(data, &Config)
 	return &Config, err
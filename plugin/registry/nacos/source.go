@@ -0,0 +1,41 @@
+package nacos
+
+import (
+	"context"
+	"github.com/y1015860449/gotoolkit/config"
+)
+
+// ConfigSource nacos 配置, 作为 config.Source 使用
+type ConfigSource struct {
+	client *NacosClient
+	dataId string
+	group  string
+}
+
+func (client *NacosClient) ConfigSource(dataId, group string) *ConfigSource {
+	return &ConfigSource{client: client, dataId: dataId, group: group}
+}
+
+func (s *ConfigSource) Read() ([]byte, error) {
+	data, err := s.client.GetConfig(s.dataId, s.group)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+func (s *ConfigSource) Watch(ctx context.Context, onChange func(data []byte)) error {
+	err := s.client.ListenConfig(s.dataId, s.group, func(namespace, group, dataId, data string) {
+		onChange([]byte(data))
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		_ = s.client.CancelListenConfig(s.dataId, s.group)
+	}()
+	return nil
+}
+
+var _ config.Source = (*ConfigSource)(nil)
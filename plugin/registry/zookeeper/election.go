@@ -0,0 +1,152 @@
+package zookeeper
+
+import (
+	"context"
+	"github.com/go-zookeeper/zk"
+	"github.com/y1015860449/gotoolkit/election"
+	"github.com/y1015860449/gotoolkit/utils"
+	"log"
+	"strings"
+	"time"
+)
+
+const electionPrefix = "n-"
+
+// Election 基于临时顺序节点的选举, 序号最小者为主节点, 会话过期节点删除即失去主节点
+type Election struct {
+	*election.Status
+	cli    *ZkClient
+	path   string
+	opts   election.Options
+	runner election.Runner
+}
+
+func (cli *ZkClient) NewElection(path string, opts ...election.Option) *Election {
+	o := election.NewOptions(opts...)
+	if len(o.Value) <= 0 {
+		o.Value = utils.GetUUID()
+	}
+	return &Election{
+		Status: election.NewStatus(o),
+		cli:    cli,
+		path:   strings.TrimSuffix(path, "/"),
+		opts:   o,
+	}
+}
+
+func (e *Election) Campaign(ctx context.Context) error {
+	ctx, ok := e.runner.Start(ctx)
+	if !ok {
+		return election.ErrCampaigning
+	}
+	defer e.runner.Finish()
+	for ctx.Err() == nil {
+		node, err := e.create()
+		if err != nil {
+			log.Printf("election create node err(%+v)", err)
+			election.Sleep(ctx, e.opts.RetryInterval)
+			continue
+		}
+		if err = e.wait(ctx, node); err != nil {
+			_ = e.cli.conn.Delete(e.path+"/"+node, -1)
+			if ctx.Err() == nil {
+				log.Printf("election wait err(%+v)", err)
+				election.Sleep(ctx, e.opts.RetryInterval)
+			}
+			continue
+		}
+		e.Elected()
+		e.hold(ctx, node)
+		e.Revoked()
+		// 主动让位, 会话已过期时节点已被删除
+		_ = e.cli.conn.Delete(e.path+"/"+node, -1)
+	}
+	return nil
+}
+
+func (e *Election) Resign(ctx context.Context) error {
+	return e.runner.Stop(ctx)
+}
+
+// Leader 当前主节点的标识
+func (e *Election) Leader() (string, error) {
+	nodes, err := e.cli.sequenceNodes(e.path, electionPrefix)
+	if err != nil {
+		return "", err
+	}
+	if len(nodes) <= 0 {
+		return "", zk.ErrNoNode
+	}
+	data, _, err := e.cli.conn.Get(e.path + "/" + nodes[0])
+	return string(data), err
+}
+
+func (e *Election) create() (string, error) {
+	if err := e.cli.EnsurePath(e.path); err != nil {
+		return "", err
+	}
+	// 与 Locker 相同, 使用保护节点避免连接断开时遗留无主节点
+	path, err := e.cli.conn.CreateProtectedEphemeralSequential(e.path+"/"+electionPrefix, []byte(e.opts.Value), zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return "", err
+	}
+	return path[len(e.path)+1:], nil
+}
+
+// wait 阻塞直到 node 排在首位
+func (e *Election) wait(ctx context.Context, node string) error {
+	for {
+		prev, err := e.cli.predecessor(e.path, electionPrefix, node)
+		if err != nil {
+			return err
+		}
+		if len(prev) <= 0 {
+			return nil
+		}
+		// 只监听前一个节点, 避免羊群效应
+		exist, _, ch, err := e.cli.conn.ExistsW(e.path + "/" + prev)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// hold 监听自己的节点, 节点被删除或连接断开时返回. 与 WorkerLeaser 相同,
+// 断开期间服务端会话可能过期并选出新主节点, 因此断开即让位, 避免同时存在两个主节点
+func (e *Election) hold(ctx context.Context, node string) {
+	ticker := time.NewTicker(e.cli.checkInterval())
+	defer ticker.Stop()
+	for {
+		exist, _, ch, err := e.cli.conn.ExistsW(e.path + "/" + node)
+		if err != nil || !exist {
+			log.Printf("election node(%v) lost err(%+v)", node, err)
+			return
+		}
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if e.cli.conn.State() != zk.StateHasSession {
+					log.Printf("election node(%v) disconnected", node)
+					return
+				}
+			case ev := <-ch:
+				if ev.Type == zk.EventNodeDeleted || ev.Type == zk.EventNotWatching {
+					log.Printf("election node(%v) lost event(%v)", node, ev.Type)
+					return
+				}
+				break wait
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package zookeeper
+
+import (
+	"context"
+	"github.com/go-zookeeper/zk"
+	"github.com/y1015860449/gotoolkit/locker"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	lockPrefix      = "lock-"
+	protectedPrefix = "_c_" // 与 zk 库保护节点前缀一致
+)
+
+// Locker 基于临时顺序节点的分布式锁, 会话心跳维持节点, 无需续期
+type Locker struct {
+	cli  *ZkClient
+	path string
+	opts locker.Options
+	mtx  sync.Mutex
+	node string
+}
+
+func (cli *ZkClient) NewLocker(path string, opts ...locker.Option) *Locker {
+	return &Locker{
+		cli:  cli,
+		path: strings.TrimSuffix(path, "/"),
+		opts: locker.NewOptions(opts...),
+	}
+}
+
+// create 创建自己的排队节点, 返回节点名.
+// 使用带 guid 前缀的保护节点, 创建时连接断开可找回已创建的节点, 避免遗留无主节点导致死锁
+func (l *Locker) create() (string, error) {
+	if err := l.cli.EnsurePath(l.path); err != nil {
+		return "", err
+	}
+	path, err := l.cli.conn.CreateProtectedEphemeralSequential(l.path+"/"+lockPrefix, nil, zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return "", err
+	}
+	return path[len(l.path)+1:], nil
+}
+
+// predecessor 返回 path 下 prefix 顺序节点中排在 node 前面的节点, 为空表示排在首位
+func (cli *ZkClient) predecessor(path, prefix, node string) (string, error) {
+	nodes, err := cli.sequenceNodes(path, prefix)
+	if err != nil {
+		return "", err
+	}
+	prev := ""
+	for _, child := range nodes {
+		if child == node {
+			return prev, nil
+		}
+		prev = child
+	}
+	return "", zk.ErrNoNode
+}
+
+// sequenceNodes 按顺序号排序的 prefix 子节点, 包括带保护前缀的节点
+func (cli *ZkClient) sequenceNodes(path, prefix string) ([]string, error) {
+	children, _, err := cli.conn.Children(path)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []string
+	for _, child := range children {
+		if strings.HasPrefix(trimProtected(child), prefix) {
+			nodes = append(nodes, child)
+		}
+	}
+	// 顺序号定长补零, 去掉保护前缀后按字符串排序即可
+	sort.Slice(nodes, func(i, j int) bool {
+		return trimProtected(nodes[i]) < trimProtected(nodes[j])
+	})
+	return nodes, nil
+}
+
+// trimProtected 去掉 CreateProtectedEphemeralSequential 添加的 "_c_<guid>-" 前缀
+func trimProtected(node string) string {
+	const n = len(protectedPrefix) + 32 + 1
+	if strings.HasPrefix(node, protectedPrefix) && len(node) > n && node[n-1] == '-' {
+		return node[n:]
+	}
+	return node
+}
+
+func (l *Locker) release(node string) {
+	_ = l.cli.conn.Delete(l.path+"/"+node, -1)
+}
+
+func (l *Locker) Lock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if len(l.node) > 0 {
+		return locker.ErrLocked
+	}
+	ctx, cancel := locker.WithTimeout(ctx, l.opts)
+	defer cancel()
+	node, err := l.create()
+	if err != nil {
+		return err
+	}
+	for {
+		prev, err := l.cli.predecessor(l.path, lockPrefix, node)
+		if err != nil {
+			l.release(node)
+			return err
+		}
+		if len(prev) <= 0 {
+			l.node = node
+			return nil
+		}
+		// 只监听前一个节点, 避免羊群效应
+		exist, _, ch, err := l.cli.conn.ExistsW(l.path + "/" + prev)
+		if err != nil {
+			l.release(node)
+			return err
+		}
+		if !exist {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			l.release(node)
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+func (l *Locker) TryLock(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if len(l.node) > 0 {
+		return false, locker.ErrLocked
+	}
+	node, err := l.create()
+	if err != nil {
+		return false, err
+	}
+	prev, err := l.cli.predecessor(l.path, lockPrefix, node)
+	if err != nil || len(prev) > 0 {
+		l.release(node)
+		return false, err
+	}
+	l.node = node
+	return true, nil
+}
+
+func (l *Locker) Unlock(ctx context.Context) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if len(l.node) <= 0 {
+		return locker.ErrNotLocked
+	}
+	node := l.node
+	l.node = ""
+	err := l.cli.conn.Delete(l.path+"/"+node, -1)
+	if err == zk.ErrNoNode {
+		return locker.ErrLockLost
+	}
+	return err
+}
@@ -0,0 +1,309 @@
+package zookeeper
+
+import (
+	"context"
+	"github.com/go-zookeeper/zk"
+	"github.com/y1015860449/gotoolkit/utils"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////
+// 屏障: 节点存在时所有等待者阻塞, 删除后放行
+////////////////////////////////////////////////
+
+type Barrier struct {
+	cli  *ZkClient
+	path string
+}
+
+func (cli *ZkClient) NewBarrier(path string) *Barrier {
+	return &Barrier{cli: cli, path: path}
+}
+
+// Set 设置屏障
+func (b *Barrier) Set() error {
+	_, err := b.cli.CreateRecursive(b.path, 0, nil)
+	if err == zk.ErrNodeExists {
+		return nil
+	}
+	return err
+}
+
+// Remove 移除屏障, 放行所有等待者
+func (b *Barrier) Remove() error {
+	err := b.cli.conn.Delete(b.path, -1)
+	if err == zk.ErrNoNode {
+		return nil
+	}
+	return err
+}
+
+// Wait 阻塞直到屏障被移除
+func (b *Barrier) Wait(ctx context.Context) error {
+	for {
+		exist, _, ch, err := b.cli.conn.ExistsW(b.path)
+		if err != nil {
+			return err
+		}
+		if !exist {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+////////////////////////////////////////////////
+// 双屏障: size 个参与者都 Enter 后一起开始, 都 Leave 后一起结束
+////////////////////////////////////////////////
+
+const readyNode = "ready"
+
+type DoubleBarrier struct {
+	cli  *ZkClient
+	path string
+	size int
+	node string
+}
+
+func (cli *ZkClient) NewDoubleBarrier(path string, size int) *DoubleBarrier {
+	return &DoubleBarrier{
+		cli:  cli,
+		path: strings.TrimSuffix(path, "/"),
+		size: size,
+		node: utils.GetUUID(),
+	}
+}
+
+// participants 除 ready 外的子节点
+func (b *DoubleBarrier) participants() ([]string, <-chan zk.Event, error) {
+	children, _, ch, err := b.cli.conn.ChildrenW(b.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodes := make([]string, 0, len(children))
+	for _, child := range children {
+		if child != readyNode {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes, ch, nil
+}
+
+// Enter 阻塞直到 size 个参与者进入
+func (b *DoubleBarrier) Enter(ctx context.Context) error {
+	readyPath := b.path + "/" + readyNode
+	// 先监听 ready, 避免创建自己的节点后错过
+	exist, _, readyCh, err := b.cli.conn.ExistsW(readyPath)
+	if err != nil && err != zk.ErrNoNode {
+		return err
+	}
+	if _, err = b.cli.CreateRecursive(b.path+"/"+b.node, zk.FlagEphemeral, nil); err != nil {
+		return err
+	}
+	if exist {
+		return nil
+	}
+	nodes, _, err := b.participants()
+	if err != nil {
+		return err
+	}
+	if len(nodes) >= b.size {
+		if _, err = b.cli.conn.Create(readyPath, nil, 0, zk.WorldACL(zk.PermAll)); err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		_ = b.cli.conn.Delete(b.path+"/"+b.node, -1)
+		return ctx.Err()
+	case <-readyCh:
+		return nil
+	}
+}
+
+// Leave 删除自己的节点, 阻塞直到所有参与者离开
+func (b *DoubleBarrier) Leave(ctx context.Context) error {
+	if err := b.cli.conn.Delete(b.path+"/"+b.node, -1); err != nil && err != zk.ErrNoNode {
+		return err
+	}
+	for {
+		nodes, ch, err := b.participants()
+		if err != nil {
+			return err
+		}
+		if len(nodes) <= 0 {
+			// 最后离开的清理 ready, 供下一轮使用
+			_ = b.cli.conn.Delete(b.path+"/"+readyNode, -1)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+////////////////////////////////////////////////
+// 计数器: 节点数据为十进制数, 按版本号乐观更新
+////////////////////////////////////////////////
+
+type Counter struct {
+	cli  *ZkClient
+	path string
+}
+
+func (cli *ZkClient) NewCounter(path string) *Counter {
+	return &Counter{cli: cli, path: path}
+}
+
+func (c *Counter) get() (int64, int32, error) {
+	data, stat, err := c.cli.conn.Get(c.path)
+	if err == zk.ErrNoNode {
+		return 0, -1, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(data) <= 0 {
+		return 0, stat.Version, nil
+	}
+	n, err := strconv.ParseInt(string(data), 10, 64)
+	return n, stat.Version, err
+}
+
+func (c *Counter) Get() (int64, error) {
+	n, _, err := c.get()
+	return n, err
+}
+
+// Add 增加 delta, 返回增加后的值
+func (c *Counter) Add(delta int64) (int64, error) {
+	for {
+		n, version, err := c.get()
+		if err != nil {
+			return 0, err
+		}
+		n += delta
+		data := []byte(strconv.FormatInt(n, 10))
+		if version < 0 {
+			_, err = c.cli.CreateRecursive(c.path, 0, data)
+		} else {
+			_, err = c.cli.conn.Set(c.path, data, version)
+		}
+		switch err {
+		case nil:
+			return n, nil
+		case zk.ErrBadVersion, zk.ErrNodeExists, zk.ErrNoNode:
+			// 并发修改, 重试
+			continue
+		default:
+			return 0, err
+		}
+	}
+}
+
+////////////////////////////////////////////////
+// 分布式队列: 持久顺序节点, 按序号先进先出
+////////////////////////////////////////////////
+
+const queuePrefix = "qn-"
+
+type Queue struct {
+	cli  *ZkClient
+	path string
+}
+
+func (cli *ZkClient) NewQueue(path string) *Queue {
+	return &Queue{cli: cli, path: strings.TrimSuffix(path, "/")}
+}
+
+func (q *Queue) Put(data []byte) error {
+	_, err := q.cli.CreateRecursive(q.path+"/"+queuePrefix, zk.FlagSequence, data)
+	return err
+}
+
+// Poll 取出队首元素, 队列为空时返回 nil
+func (q *Queue) Poll() ([]byte, error) {
+	data, _, err := q.poll(false)
+	return data, err
+}
+
+// Take 阻塞直到取出队首元素
+func (q *Queue) Take(ctx context.Context) ([]byte, error) {
+	for {
+		data, ch, err := q.poll(true)
+		if err != nil || data != nil {
+			return data, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+// Len 队列长度
+func (q *Queue) Len() (int, error) {
+	nodes, err := q.cli.sequenceNodes(q.path, queuePrefix)
+	if err == zk.ErrNoNode {
+		return 0, nil
+	}
+	return len(nodes), err
+}
+
+// poll 按序尝试删除节点, 删除成功者获得该元素; watch 为 true 时队列为空返回子节点监听
+func (q *Queue) poll(watch bool) ([]byte, <-chan zk.Event, error) {
+	var children []string
+	var ch <-chan zk.Event
+	var err error
+	if watch {
+		if err = q.cli.EnsurePath(q.path); err != nil {
+			return nil, nil, err
+		}
+		children, _, ch, err = q.cli.conn.ChildrenW(q.path)
+	} else {
+		children, _, err = q.cli.conn.Children(q.path)
+	}
+	if err == zk.ErrNoNode {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(children)
+	for _, child := range children {
+		if !strings.HasPrefix(child, queuePrefix) {
+			continue
+		}
+		path := q.path + "/" + child
+		data, stat, err := q.cli.conn.Get(path)
+		if err == zk.ErrNoNode {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		err = q.cli.conn.Delete(path, stat.Version)
+		if err == zk.ErrNoNode {
+			// 被其他消费者取走
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if data == nil {
+			data = []byte{}
+		}
+		return data, nil, nil
+	}
+	return nil, ch, nil
+}
@@ -0,0 +1,74 @@
+package zookeeper
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-zookeeper/zk"
+	"github.com/y1015860449/gotoolkit/config"
+	"log"
+	"time"
+)
+
+// ConfigSource zookeeper 节点数据作为配置, 作为 config.Source 使用
+type ConfigSource struct {
+	cli  *ZkClient
+	path string
+}
+
+func (cli *ZkClient) ConfigSource(path string) *ConfigSource {
+	return &ConfigSource{cli: cli, path: path}
+}
+
+func (s *ConfigSource) Read() ([]byte, error) {
+	data, _, err := s.cli.conn.Get(s.path)
+	if err == zk.ErrNoNode {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *ConfigSource) Watch(ctx context.Context, onChange func(data []byte)) error {
+	last, err := s.Read()
+	if err != nil {
+		return err
+	}
+	go func() {
+		for ctx.Err() == nil {
+			data, ch, err := s.watch()
+			if err != nil {
+				log.Printf("config watch path(%v) err(%+v)", s.path, err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+			if !bytes.Equal(data, last) {
+				last = data
+				onChange(data)
+			}
+			// watch 是一次性的, 触发后重新注册
+			select {
+			case <-ctx.Done():
+			case <-ch:
+			}
+		}
+	}()
+	return nil
+}
+
+// watch 节点不存在时监听创建
+func (s *ConfigSource) watch() ([]byte, <-chan zk.Event, error) {
+	data, _, ch, err := s.cli.conn.GetW(s.path)
+	if err != zk.ErrNoNode {
+		return data, ch, err
+	}
+	exist, _, ch, err := s.cli.conn.ExistsW(s.path)
+	if err != nil || !exist {
+		return nil, ch, err
+	}
+	// 刚好被创建
+	return s.watch()
+}
+
+var _ config.Source = (*ConfigSource)(nil)
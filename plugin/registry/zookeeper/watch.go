@@ -0,0 +1,98 @@
+package zookeeper
+
+import (
+	"context"
+	"github.com/go-zookeeper/zk"
+	"time"
+)
+
+type DataEvent struct {
+	Exists bool
+	Data   []byte
+	Stat   *zk.Stat
+	Err    error // 出错后会自动重试, 仅用于通知
+}
+
+type ChildrenEvent struct {
+	Exists   bool
+	Children []string
+	Err      error
+}
+
+// WatchData 监听节点数据, 先发送当前数据, 之后每次变化(含创建/删除)发送一次; watch 触发后自动重新注册, ctx 取消时关闭通道
+func (cli *ZkClient) WatchData(ctx context.Context, path string) <-chan DataEvent {
+	ch := make(chan DataEvent, 1)
+	go func() {
+		defer close(ch)
+		for ctx.Err() == nil {
+			var ev DataEvent
+			data, stat, wch, err := cli.conn.GetW(path)
+			if err == zk.ErrNoNode {
+				// 节点不存在时监听创建
+				var exist bool
+				exist, _, wch, err = cli.conn.ExistsW(path)
+				if err == nil && exist {
+					continue
+				}
+			} else if err == nil {
+				ev = DataEvent{Exists: true, Data: data, Stat: stat}
+			}
+			if err != nil {
+				ev.Err = err
+			}
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+			cli.waitWatch(ctx, wch, err)
+		}
+	}()
+	return ch
+}
+
+// WatchChildren 监听子节点列表, 用法同 WatchData
+func (cli *ZkClient) WatchChildren(ctx context.Context, path string) <-chan ChildrenEvent {
+	ch := make(chan ChildrenEvent, 1)
+	go func() {
+		defer close(ch)
+		for ctx.Err() == nil {
+			var ev ChildrenEvent
+			children, _, wch, err := cli.conn.ChildrenW(path)
+			if err == zk.ErrNoNode {
+				var exist bool
+				exist, _, wch, err = cli.conn.ExistsW(path)
+				if err == nil && exist {
+					continue
+				}
+			} else if err == nil {
+				ev = ChildrenEvent{Exists: true, Children: children}
+			}
+			if err != nil {
+				ev.Err = err
+			}
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+				return
+			}
+			cli.waitWatch(ctx, wch, err)
+		}
+	}()
+	return ch
+}
+
+// waitWatch 等待 watch 触发, 出错时等待1s后重试
+func (cli *ZkClient) waitWatch(ctx context.Context, wch <-chan zk.Event, err error) {
+	if err != nil || wch == nil {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-wch:
+	}
+}
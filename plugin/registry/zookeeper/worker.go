@@ -0,0 +1,115 @@
+package zookeeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-zookeeper/zk"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorkerLeaser 以临时节点 path/<id> 占用 worker id, 会话过期即释放, 实现 idMaker.WorkerLeaser
+type WorkerLeaser struct {
+	cli    *ZkClient
+	path   string
+	mtx    sync.Mutex
+	node   string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (cli *ZkClient) NewWorkerLeaser(path string) *WorkerLeaser {
+	return &WorkerLeaser{cli: cli, path: strings.TrimSuffix(path, "/")}
+}
+
+func (w *WorkerLeaser) Acquire(ctx context.Context, max int64) (int64, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if len(w.node) > 0 {
+		return 0, errors.New("worker id already acquired")
+	}
+	if err := w.cli.EnsurePath(w.path); err != nil {
+		return 0, err
+	}
+	host, _ := os.Hostname()
+	start := rand.Int63n(max)
+	for i := int64(0); i < max; i++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		id := (start + i) % max
+		node := fmt.Sprintf("%s/%d", w.path, id)
+		_, err := w.cli.conn.Create(node, []byte(host), zk.FlagEphemeral, zk.WorldACL(zk.PermAll))
+		if err == zk.ErrNodeExists {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		hctx, cancel := context.WithCancel(context.Background())
+		// 每次租用新建 done, Release 后可以再次 Acquire
+		w.node, w.cancel, w.done = node, cancel, make(chan struct{})
+		go w.hold(hctx, node, w.done)
+		return id, nil
+	}
+	return 0, errors.New("no free worker id")
+}
+
+// hold 节点被删除或连接断开时关闭 done. 会话过期的通知要等重连后才能收到,
+// 而发现断开时会话可能已接近过期, 因此断开即视为丢失
+func (w *WorkerLeaser) hold(ctx context.Context, node string, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(w.cli.checkInterval())
+	defer ticker.Stop()
+	for {
+		exist, _, ch, err := w.cli.conn.ExistsW(node)
+		if err != nil || !exist {
+			return
+		}
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if w.cli.conn.State() != zk.StateHasSession {
+					log.Printf("worker lease node(%v) disconnected", node)
+					return
+				}
+			case ev := <-ch:
+				if ev.Type == zk.EventNodeDeleted || ev.Type == zk.EventNotWatching {
+					return
+				}
+				break wait
+			}
+		}
+	}
+}
+
+// Done 当前租约丢失或释放时关闭, Acquire 之前返回 nil
+func (w *WorkerLeaser) Done() <-chan struct{} {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.done
+}
+
+func (w *WorkerLeaser) Release(ctx context.Context) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if len(w.node) <= 0 {
+		return nil
+	}
+	w.cancel()
+	node := w.node
+	w.node, w.cancel = "", nil
+	err := w.cli.conn.Delete(node, -1)
+	if err == zk.ErrNoNode {
+		return nil
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package zookeeper
 
 import (
 	"github.com/go-zookeeper/zk"
+	"strings"
 	"time"
 )
 
@@ -24,10 +25,18 @@ func InitZookeeper(c *ZkConfig) (*ZkClient, error) {
 	return &ZkClient{
 		conn:   conn,
 		events: event,
-		conf:   nil,
+		conf:   c,
 	}, nil
 }
 
+// checkInterval 检查连接状态的间隔, 会话超时的1/10
+func (cli *ZkClient) checkInterval() time.Duration {
+	if cli.conf == nil || cli.conf.Timeout < time.Second {
+		return 100 * time.Millisecond
+	}
+	return cli.conf.Timeout / 10
+}
+
 func (cli *ZkClient) Close() {
 	cli.conn.Close()
 }
@@ -43,6 +52,36 @@ func (cli *ZkClient) Create(path string, flags int32, data []byte) error {
 	return err
 }
 
+// EnsurePath 逐级创建持久节点, 已存在时忽略
+func (cli *ZkClient) EnsurePath(path string) error {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	cur := ""
+	for _, part := range parts {
+		cur += "/" + part
+		exist, _, err := cli.conn.Exists(cur)
+		if err != nil {
+			return err
+		}
+		if exist {
+			continue
+		}
+		if _, err = cli.conn.Create(cur, nil, 0, zk.WorldACL(zk.PermAll)); err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateRecursive 父节点不存在时逐级创建(持久节点), 返回实际创建的路径(顺序节点带序号)
+func (cli *ZkClient) CreateRecursive(path string, flags int32, data []byte) (string, error) {
+	if idx := strings.LastIndex(path, "/"); idx > 0 {
+		if err := cli.EnsurePath(path[:idx]); err != nil {
+			return "", err
+		}
+	}
+	return cli.conn.Create(path, data, flags, zk.WorldACL(zk.PermAll))
+}
+
 func (cli *ZkClient) Modify(path string, data []byte) error {
 	_, sate, _ := cli.conn.Get(path)
 	_, err := cli.conn.Set(path, data, sate.Version)
@@ -0,0 +1,173 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// 每处理 sweepEvery 次请求清理一次空闲 key
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket 本地令牌桶, 仅限单进程
+type TokenBucket struct {
+	limit   Limit
+	mtx     sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewTokenBucket(limit Limit) (*TokenBucket, error) {
+	limit, err := limit.Check()
+	if err != nil {
+		return nil, err
+	}
+	return &TokenBucket{limit: limit, buckets: make(map[string]*bucket), now: time.Now}, nil
+}
+
+// rate 每纳秒产生的令牌数
+func (tb *TokenBucket) rate() float64 {
+	return float64(tb.limit.Rate) / float64(tb.limit.Period)
+}
+
+// fillTime 从空桶到满桶的时间, 超过该时间未访问的 key 等同于新建
+func (tb *TokenBucket) fillTime() time.Duration {
+	return time.Duration(float64(tb.limit.Burst) / tb.rate())
+}
+
+func (tb *TokenBucket) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tb.mtx.Lock()
+	defer tb.mtx.Unlock()
+	now := tb.now()
+	tb.sweep(now)
+	b, ok := tb.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(tb.limit.Burst), last: now}
+		tb.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(tb.limit.Burst), b.tokens+float64(elapsed)*tb.rate())
+		b.last = now
+	}
+	if n > tb.limit.Burst {
+		return &Result{Remaining: int64(b.tokens), RetryAfter: -1}, nil
+	}
+	if b.tokens >= float64(n) {
+		b.tokens -= float64(n)
+		return &Result{Allowed: true, Remaining: int64(b.tokens)}, nil
+	}
+	wait := time.Duration(math.Ceil((float64(n) - b.tokens) / tb.rate()))
+	return &Result{Remaining: int64(b.tokens), RetryAfter: wait}, nil
+}
+
+func (tb *TokenBucket) sweep(now time.Time) {
+	if tb.calls++; tb.calls < sweepEvery {
+		return
+	}
+	tb.calls = 0
+	idle := tb.fillTime()
+	for key, b := range tb.buckets {
+		if now.Sub(b.last) > idle {
+			delete(tb.buckets, key)
+		}
+	}
+}
+
+type window struct {
+	start time.Time
+	cur   int64
+	prev  int64
+}
+
+// SlidingWindow 本地滑动窗口, 按前一个窗口的计数加权估算, 仅限单进程
+type SlidingWindow struct {
+	limit   Limit
+	mtx     sync.Mutex
+	windows map[string]*window
+	calls   int
+	now     func() time.Time
+}
+
+func NewSlidingWindow(limit Limit) (*SlidingWindow, error) {
+	limit, err := limit.Check()
+	if err != nil {
+		return nil, err
+	}
+	return &SlidingWindow{limit: limit, windows: make(map[string]*window), now: time.Now}, nil
+}
+
+func (sw *SlidingWindow) AllowN(ctx context.Context, key string, n int64) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sw.mtx.Lock()
+	defer sw.mtx.Unlock()
+	now := sw.now()
+	sw.sweep(now)
+	size := sw.limit.Period
+	start := now.Truncate(size)
+	w, ok := sw.windows[key]
+	if !ok {
+		w = &window{start: start}
+		sw.windows[key] = w
+	}
+	if !w.start.Equal(start) {
+		if start.Sub(w.start) == size {
+			w.prev = w.cur
+		} else {
+			w.prev = 0
+		}
+		w.cur, w.start = 0, start
+	}
+	res := slidingWindow(sw.limit.Rate, size, now.Sub(start), w.prev, w.cur, n)
+	if res.Allowed {
+		w.cur += n
+	}
+	return res, nil
+}
+
+// slidingWindow 计算是否放行, elapsed 为当前窗口已过去的时间
+func slidingWindow(limit int64, size, elapsed time.Duration, prev, cur, n int64) *Result {
+	weight := float64(size-elapsed) / float64(size)
+	count := float64(prev)*weight + float64(cur)
+	remaining := int64(math.Max(0, math.Floor(float64(limit)-count)))
+	if n > limit {
+		return &Result{Remaining: remaining, RetryAfter: -1}
+	}
+	if count+float64(n) <= float64(limit) {
+		return &Result{Allowed: true, Remaining: int64(math.Max(0, float64(remaining-n)))}
+	}
+	if cur+n > limit || prev == 0 {
+		// 等到下个窗口, 当前窗口计数变为 prev
+		return &Result{Remaining: remaining, RetryAfter: size - elapsed}
+	}
+	// prev 的权重衰减到 (limit-cur-n)/prev 时放行
+	need := 1 - float64(limit-cur-n)/float64(prev)
+	wait := time.Duration(math.Ceil(need*float64(size))) - elapsed
+	if wait <= 0 {
+		wait = time.Millisecond
+	}
+	return &Result{Remaining: remaining, RetryAfter: wait}
+}
+
+func (sw *SlidingWindow) sweep(now time.Time) {
+	if sw.calls++; sw.calls < sweepEvery {
+		return
+	}
+	sw.calls = 0
+	for key, w := range sw.windows {
+		if now.Sub(w.start) > 2*sw.limit.Period {
+			delete(sw.windows, key)
+		}
+	}
+}
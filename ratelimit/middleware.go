@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// KeyFunc 返回限流 key, 为空时不限流
+type KeyFunc func(ctx context.Context, method string) string
+
+// ByMethod 按 gRPC 方法限流
+func ByMethod(prefix string) KeyFunc {
+	return func(ctx context.Context, method string) string {
+		return prefix + method
+	}
+}
+
+// ByMetadata 按请求 metadata 中的字段(如用户id)限流, 没有该字段时不限流
+func ByMetadata(prefix, field string) KeyFunc {
+	return func(ctx context.Context, method string) string {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return ""
+		}
+		if vals := md.Get(field); len(vals) > 0 && len(vals[0]) > 0 {
+			return prefix + vals[0]
+		}
+		return ""
+	}
+}
+
+// ByPeer 按客户端 ip 限流
+func ByPeer(prefix string) KeyFunc {
+	return func(ctx context.Context, method string) string {
+		p, ok := peer.FromContext(ctx)
+		if !ok || p.Addr == nil {
+			return ""
+		}
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return prefix + host
+	}
+}
+
+// allow 限流器出错时放行, 避免限流服务故障影响业务
+func allow(ctx context.Context, l Limiter, key string) *Result {
+	if len(key) <= 0 {
+		return &Result{Allowed: true}
+	}
+	res, err := l.AllowN(ctx, key, 1)
+	if err != nil {
+		log.Printf("ratelimit key(%v) err(%+v)", key, err)
+		return &Result{Allowed: true}
+	}
+	return res
+}
+
+func deny(key string, res *Result) error {
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, key(%s) retry after %v", key, res.RetryAfter)
+}
+
+func UnaryServerInterceptor(l Limiter, keyFn KeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := keyFn(ctx, info.FullMethod)
+		if res := allow(ctx, l, key); !res.Allowed {
+			return nil, deny(key, res)
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor(l Limiter, keyFn KeyFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		key := keyFn(ss.Context(), info.FullMethod)
+		if res := allow(ss.Context(), l, key); !res.Allowed {
+			return deny(key, res)
+		}
+		return handler(srv, ss)
+	}
+}
+
+// HttpKeyFunc 返回限流 key, 为空时不限流
+type HttpKeyFunc func(r *http.Request) string
+
+// ByPath 按请求路径限流
+func ByPath(prefix string) HttpKeyFunc {
+	return func(r *http.Request) string {
+		return prefix + r.URL.Path
+	}
+}
+
+// ByHeader 按请求头(如用户id, token)限流, 没有该请求头时不限流
+func ByHeader(prefix, header string) HttpKeyFunc {
+	return func(r *http.Request) string {
+		if v := r.Header.Get(header); len(v) > 0 {
+			return prefix + v
+		}
+		return ""
+	}
+}
+
+// ByRemoteIP 按客户端 ip 限流, 不解析 X-Forwarded-For
+func ByRemoteIP(prefix string) HttpKeyFunc {
+	return func(r *http.Request) string {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return prefix + host
+	}
+}
+
+// HttpMiddleware 超过限制时返回 429 和 Retry-After 头
+func HttpMiddleware(l Limiter, keyFn HttpKeyFunc, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res := allow(r.Context(), l, keyFn(r))
+		if !res.Allowed {
+			if res.RetryAfter > 0 {
+				secs := int64((res.RetryAfter + time.Second - 1) / time.Second)
+				w.Header().Set("Retry-After", strconv.FormatInt(secs, 10))
+			}
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
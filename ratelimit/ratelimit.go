@@ -0,0 +1,79 @@
+// Package ratelimit 限流统一接口和本地实现, redis 实现见 cache/goredis/redisLimit
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrLimit       = errors.New("ratelimit: limit is err")    // Limit 参数错误
+	ErrExceedBurst = errors.New("ratelimit: n exceeds burst") // 请求数超过上限, 永远不会放行
+)
+
+// Result RetryAfter 为0表示已放行, 小于0表示请求数超过上限永远不会放行
+type Result struct {
+	Allowed    bool
+	Remaining  int64
+	RetryAfter time.Duration
+}
+
+// Limiter 按 key 限流, 不同 key 互不影响
+type Limiter interface {
+	// AllowN 请求 n 个许可, 不阻塞; ctx 已取消或超时时返回 ctx.Err(), 不消耗许可
+	AllowN(ctx context.Context, key string, n int64) (*Result, error)
+}
+
+// Allow 请求1个许可
+func Allow(ctx context.Context, l Limiter, key string) (*Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+// Wait 阻塞直到获得 n 个许可
+func Wait(ctx context.Context, l Limiter, key string, n int64) error {
+	for {
+		res, err := l.AllowN(ctx, key, n)
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			return nil
+		}
+		if res.RetryAfter < 0 {
+			return ErrExceedBurst
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(res.RetryAfter):
+		}
+	}
+}
+
+// Limit 每 Period 产生 Rate 个许可; 令牌桶最多积累 Burst 个, 为0时等于 Rate; 滑动窗口中 Period 为窗口长度
+type Limit struct {
+	Rate   int64
+	Period time.Duration
+	Burst  int64
+}
+
+// PerSecond 每秒 rate 个
+func PerSecond(rate int64) Limit {
+	return Limit{Rate: rate, Period: time.Second}
+}
+
+// PerMinute 每分钟 rate 个
+func PerMinute(rate int64) Limit {
+	return Limit{Rate: rate, Period: time.Minute}
+}
+
+func (l Limit) Check() (Limit, error) {
+	if l.Rate <= 0 || l.Period <= 0 {
+		return l, ErrLimit
+	}
+	if l.Burst <= 0 {
+		l.Burst = l.Rate
+	}
+	return l, nil
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	tb, err := NewTokenBucket(Limit{Rate: 10, Period: time.Second, Burst: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb.now = clock.Now
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if res, _ := tb.AllowN(ctx, "a", 1); !res.Allowed {
+			t.Fatalf("request %d denied", i)
+		}
+	}
+	res, _ := tb.AllowN(ctx, "a", 1)
+	if res.Allowed || res.RetryAfter != 100*time.Millisecond {
+		t.Fatalf("unexpected %+v", res)
+	}
+	// 其他 key 不受影响
+	if res, _ = tb.AllowN(ctx, "b", 5); !res.Allowed {
+		t.Fatalf("key b denied")
+	}
+	clock.now = clock.now.Add(200 * time.Millisecond)
+	if res, _ = tb.AllowN(ctx, "a", 2); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("unexpected %+v", res)
+	}
+	if res, _ = tb.AllowN(ctx, "a", 6); res.Allowed || res.RetryAfter >= 0 {
+		t.Fatalf("unexpected %+v", res)
+	}
+	// ctx 已取消时不消耗许可
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = tb.AllowN(canceled, "b", 1); err != context.Canceled {
+		t.Fatalf("want canceled, got %v", err)
+	}
+	if _, err = NewTokenBucket(Limit{}); err != ErrLimit {
+		t.Fatalf("want ErrLimit, got %v", err)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	sw, err := NewSlidingWindow(PerSecond(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sw.now = clock.Now
+	ctx := context.Background()
+	if res, _ := sw.AllowN(ctx, "a", 10); !res.Allowed {
+		t.Fatal("denied")
+	}
+	res, _ := sw.AllowN(ctx, "a", 1)
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("unexpected %+v", res)
+	}
+	// 下个窗口过去一半, 上个窗口计数权重为0.5
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	if res, _ = sw.AllowN(ctx, "a", 5); !res.Allowed {
+		t.Fatalf("unexpected %+v", res)
+	}
+	res, _ = sw.AllowN(ctx, "a", 1)
+	if res.Allowed || res.RetryAfter != 100*time.Millisecond {
+		t.Fatalf("unexpected %+v", res)
+	}
+	// 跳过一个窗口后计数清零
+	clock.now = clock.now.Add(2 * time.Second)
+	if res, _ = sw.AllowN(ctx, "a", 10); !res.Allowed {
+		t.Fatalf("unexpected %+v", res)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	tb, _ := NewTokenBucket(Limit{Rate: 1, Period: time.Hour})
+	interceptor := UnaryServerInterceptor(tb, ByMetadata("user:", "x-user-id"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/hello.Hello/SayHello"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-user-id", "1"))
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatal(err)
+	}
+	_, err := interceptor(ctx, nil, info, handler)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("want ResourceExhausted, got %v", err)
+	}
+	// 没有用户id不限流
+	for i := 0; i < 3; i++ {
+		if _, err = interceptor(context.Background(), nil, info, handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestHttpMiddleware(t *testing.T) {
+	sw, _ := NewSlidingWindow(PerMinute(1))
+	h := HttpMiddleware(sw, ByPath("path:"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/a", nil))
+	if rec.Code != http.StatusTooManyRequests || len(rec.Header().Get("Retry-After")) <= 0 {
+		t.Fatalf("got %d %v", rec.Code, rec.Header())
+	}
+}